@@ -0,0 +1,328 @@
+package xli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/lesomnus/xli/arg"
+	"github.com/lesomnus/xli/frm"
+	"github.com/lesomnus/xli/lex"
+	"github.com/lesomnus/xli/tab"
+)
+
+// UserAliasCategory is the category user-defined aliases are listed under in
+// help and completion.
+const UserAliasCategory = "aliases"
+
+var ErrAliasLoop = errors.New("alias expands to itself")
+
+// UserAlias is a user-defined subcommand that expands to other words, like
+// "co" for "checkout --quiet".
+type UserAlias struct {
+	// Path holds the names of the commands below the root the alias is
+	// defined in; it is empty for an alias of a subcommand of the root.
+	Path []string
+	Name string
+	Args []string
+}
+
+// UserAliasStore loads and saves user-defined aliases, e.g. from a config file.
+type UserAliasStore interface {
+	Load() ([]UserAlias, error)
+	Save(vs []UserAlias) error
+}
+
+// AliasFile stores aliases in a file, one per line, as the path and name of
+// the alias followed by "=" and the words it expands to:
+//
+//	# comment
+//	co = checkout --quiet
+//	remote rm = remove --force
+//
+// The words are quoted as in a POSIX shell; see `lex.Split`. A missing file
+// holds no aliases, and `Save` rewrites the file without the comments.
+type AliasFile struct {
+	Path string
+}
+
+func (f AliasFile) Load() ([]UserAlias, error) {
+	b, err := os.ReadFile(f.Path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	vs := []UserAlias{}
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		l, r, ok := strings.Cut(line, "=")
+		names := strings.Fields(l)
+		if !ok || len(names) == 0 {
+			return nil, fmt.Errorf("%s:%d: expected NAME = ARGS", f.Path, n)
+		}
+		args, err := lex.Split(r)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", f.Path, n, err)
+		}
+
+		vs = append(vs, UserAlias{
+			Path: names[:len(names)-1],
+			Name: names[len(names)-1],
+			Args: args,
+		})
+	}
+	return vs, s.Err()
+}
+
+func (f AliasFile) Save(vs []UserAlias) error {
+	b := &strings.Builder{}
+	for _, v := range vs {
+		fmt.Fprintf(b, "%s = %s\n", strings.Join(append(slices.Clone(v.Path), v.Name), " "), lex.Join(v.Args))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(f.Path, []byte(b.String()), 0o644)
+}
+
+// userAliases returns the aliases defined in `c`, where `c` is linked to the
+// root holding the store. Errors are ignored since aliases are only listed.
+func (c *Command) userAliases() []UserAlias {
+	tree := c.Tree()
+	s := tree[0].UserAliases
+	if s == nil {
+		return nil
+	}
+	vs, err := s.Load()
+	if err != nil {
+		return nil
+	}
+
+	path := []string{}
+	for _, v := range tree[1:] {
+		path = append(path, v.Name)
+	}
+	return slices.DeleteFunc(vs, func(v UserAlias) bool {
+		return !slices.Equal(v.Path, path)
+	})
+}
+
+// expandUserAliases replaces the first word that is not a subcommand, at any
+// level, with the words of the user-defined alias of that name, until every
+// subcommand is known or there is no such alias.
+func (c *Command) expandUserAliases(args []string) ([]string, error) {
+	if c.UserAliases == nil {
+		return args, nil
+	}
+
+	var aliases []UserAlias
+	seen := []string{}
+	for {
+		f_root, err := parseFrameAll(c, args)
+		e := &ArgError{}
+		if err == nil || !errors.As(err, &e) || !errors.Is(err, ErrUnknownCmd) {
+			return args, nil
+		}
+
+		if aliases == nil {
+			aliases, err = c.UserAliases.Load()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		f := f_root.Last()
+		path := f.path()[1:]
+		name := e.Value()
+		i := slices.IndexFunc(aliases, func(v UserAlias) bool {
+			return v.Name == name && slices.Equal(v.Path, path)
+		})
+		if i < 0 {
+			return args, nil
+		}
+
+		key := strings.Join(append(path, name), " ")
+		if slices.Contains(seen, key) {
+			return nil, fmt.Errorf("%q: %w", key, ErrAliasLoop)
+		}
+		seen = append(seen, key)
+
+		args = slices.Concat(args[:e.Index()], aliases[i].Args, args[e.Index()+1:])
+	}
+}
+
+// userAliasCommands returns the aliases defined in `c` as commands for listing.
+func (c *Command) userAliasCommands() Commands {
+	vs := Commands{}
+	for _, v := range c.userAliases() {
+		vs = append(vs, &Command{
+			Category: UserAliasCategory,
+			Name:     v.Name,
+			Brief:    "= " + lex.Join(v.Args),
+		})
+	}
+	return vs
+}
+
+// NewCmdAlias returns an "alias" command that lists, sets, and removes the
+// user-defined aliases kept in the `UserAliases` of the root.
+func NewCmdAlias() *Command {
+	return &Command{
+		Name:  "alias",
+		Brief: "Manage aliases",
+		Commands: Commands{
+			newCmdAliasList(),
+			newCmdAliasSet(),
+			newCmdAliasRemove(),
+		},
+	}
+}
+
+// userAliasStore returns the store of the root of `c`.
+func userAliasStore(c *Command) (UserAliasStore, error) {
+	s := c.Root().UserAliases
+	if s == nil {
+		return nil, errors.New("aliases are not enabled")
+	}
+	return s, nil
+}
+
+func newCmdAliasList() *Command {
+	return &Command{
+		Name:  "list",
+		Brief: "List aliases",
+		Handler: OnRun(func(ctx context.Context, cmd *Command, next Next) error {
+			store, err := userAliasStore(cmd)
+			if err != nil {
+				return err
+			}
+			vs, err := store.Load()
+			if err != nil {
+				return err
+			}
+			for _, v := range vs {
+				cmd.Printf("%s = %s\n", strings.Join(append(slices.Clone(v.Path), v.Name), " "), lex.Join(v.Args))
+			}
+			return next(ctx)
+		}),
+	}
+}
+
+func newCmdAliasSet() *Command {
+	return &Command{
+		Name:  "set",
+		Brief: "Define an alias",
+		Synop: `NAME is the name of the alias, preceded by the path of the command it is defined in if it is not the root, e.g. "remote rm". ARGS are the words it expands to, quoted as in the shell, e.g. "checkout --quiet".`,
+		Args: arg.Args{
+			&arg.String{Name: "NAME", Brief: "name of the alias"},
+			&arg.String{Name: "ARGS", Brief: "words the alias expands to"},
+		},
+		Handler: OnRun(func(ctx context.Context, cmd *Command, next Next) error {
+			store, err := userAliasStore(cmd)
+			if err != nil {
+				return err
+			}
+			names := strings.Fields(arg.MustGet[string](cmd, "NAME"))
+			if len(names) == 0 {
+				return errors.New("alias name is empty")
+			}
+			args, err := lex.Split(arg.MustGet[string](cmd, "ARGS"))
+			if err != nil {
+				return err
+			}
+
+			v := UserAlias{Path: names[:len(names)-1], Name: names[len(names)-1], Args: args}
+			c := cmd.Root()
+			for _, name := range v.Path {
+				if c = c.Commands.Get(name); c == nil {
+					return fmt.Errorf("%q: %w", name, ErrUnknownCmd)
+				}
+			}
+			if c.Commands.Get(v.Name) != nil {
+				return fmt.Errorf("%q is a command", v.Name)
+			}
+
+			vs, err := store.Load()
+			if err != nil {
+				return err
+			}
+			vs = slices.DeleteFunc(vs, v.is)
+			if err := store.Save(append(vs, v)); err != nil {
+				return err
+			}
+			return next(ctx)
+		}),
+	}
+}
+
+func newCmdAliasRemove() *Command {
+	return &Command{
+		Name:    "remove",
+		Aliases: []string{"rm"},
+		Brief:   "Remove an alias",
+		Args: arg.Args{
+			&arg.String{Name: "NAME", Brief: "name of the alias", Handler: arg.OnTab[string](func(ctx context.Context, t tab.Tab) {
+				// The command is linked to the root while completing.
+				f, ok := frm.From(ctx).(*frame)
+				if !ok {
+					return
+				}
+				store, err := userAliasStore(f.c_curr)
+				if err != nil {
+					return
+				}
+				vs, err := store.Load()
+				if err != nil {
+					return
+				}
+				for _, v := range vs {
+					t.ValueD(strings.Join(append(slices.Clone(v.Path), v.Name), " "), lex.Join(v.Args))
+				}
+			})},
+		},
+		Handler: OnRun(func(ctx context.Context, cmd *Command, next Next) error {
+			store, err := userAliasStore(cmd)
+			if err != nil {
+				return err
+			}
+			names := strings.Fields(arg.MustGet[string](cmd, "NAME"))
+			if len(names) == 0 {
+				return errors.New("alias name is empty")
+			}
+			v := UserAlias{Path: names[:len(names)-1], Name: names[len(names)-1]}
+
+			vs, err := store.Load()
+			if err != nil {
+				return err
+			}
+			ws := slices.DeleteFunc(slices.Clone(vs), v.is)
+			if len(ws) == len(vs) {
+				return fmt.Errorf("alias %q is not defined", strings.Join(names, " "))
+			}
+			if err := store.Save(ws); err != nil {
+				return err
+			}
+			return next(ctx)
+		}),
+	}
+}
+
+// is reports whether `u` has the same path and name.
+func (v UserAlias) is(u UserAlias) bool {
+	return v.Name == u.Name && slices.Equal(v.Path, u.Path)
+}
@@ -0,0 +1,204 @@
+package xli_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lesomnus/xli"
+	"github.com/lesomnus/xli/arg"
+	"github.com/lesomnus/xli/flg"
+	"github.com/lesomnus/xli/internal/x"
+)
+
+type aliasStore struct {
+	vs []xli.UserAlias
+}
+
+func (s *aliasStore) Load() ([]xli.UserAlias, error) {
+	return append([]xli.UserAlias{}, s.vs...), nil
+}
+
+func (s *aliasStore) Save(vs []xli.UserAlias) error {
+	s.vs = vs
+	return nil
+}
+
+func TestUserAliases(t *testing.T) {
+	type result struct {
+		cmd   string
+		quiet bool
+		args  []string
+	}
+	newCmd := func(r *result, vs ...xli.UserAlias) *xli.Command {
+		leaf := func(name string) *xli.Command {
+			return &xli.Command{
+				Name:  name,
+				Brief: name + "-brief",
+				Flags: flg.Flags{&flg.Switch{Name: "quiet"}},
+				Args:  arg.Args{&arg.RestStrings{Name: "ARGS"}},
+				Handler: xli.OnRun(func(ctx context.Context, cmd *xli.Command, next xli.Next) error {
+					r.cmd = strings.Join(func() []string {
+						vs := []string{}
+						for _, v := range cmd.Tree() {
+							vs = append(vs, v.Name)
+						}
+						return vs
+					}(), " ")
+					r.quiet, _ = flg.Get[bool](cmd, "quiet")
+					r.args, _ = arg.Get[[]string](cmd, "ARGS")
+					return next(ctx)
+				}),
+			}
+		}
+		return &xli.Command{
+			Name:        "app",
+			UserAliases: &aliasStore{vs: vs},
+			Commands: xli.Commands{
+				leaf("checkout"),
+				&xli.Command{
+					Name: "remote",
+					Commands: xli.Commands{
+						leaf("remove"),
+					},
+				},
+				xli.NewCmdAlias(),
+			},
+		}
+	}
+
+	t.Run("expanded", x.F(func(x x.X) {
+		r := &result{}
+		c := newCmd(r, xli.UserAlias{Name: "co", Args: []string{"checkout", "--quiet"}})
+		x.NoError(c.Run(t.Context(), []string{"co", "main"}))
+		x.Equal("app checkout", r.cmd)
+		x.True(r.quiet)
+		x.Equal([]string{"main"}, r.args)
+	}))
+	t.Run("expanded at any level", x.F(func(x x.X) {
+		r := &result{}
+		c := newCmd(r, xli.UserAlias{Path: []string{"remote"}, Name: "rm", Args: []string{"remove"}})
+		x.NoError(c.Run(t.Context(), []string{"remote", "rm", "origin"}))
+		x.Equal("app remote remove", r.cmd)
+		x.Equal([]string{"origin"}, r.args)
+
+		err := c.Run(t.Context(), []string{"rm"})
+		x.True(errors.Is(err, xli.ErrUnknownCmd))
+	}))
+	t.Run("chained", x.F(func(x x.X) {
+		r := &result{}
+		c := newCmd(r,
+			xli.UserAlias{Name: "co", Args: []string{"checkout"}},
+			xli.UserAlias{Name: "q", Args: []string{"co", "--quiet"}},
+		)
+		x.NoError(c.Run(t.Context(), []string{"q"}))
+		x.Equal("app checkout", r.cmd)
+		x.True(r.quiet)
+	}))
+	t.Run("recursion", x.F(func(x x.X) {
+		r := &result{}
+		c := newCmd(r,
+			xli.UserAlias{Name: "a", Args: []string{"b"}},
+			xli.UserAlias{Name: "b", Args: []string{"a", "x"}},
+		)
+		err := c.Run(t.Context(), []string{"a"})
+		x.True(errors.Is(err, xli.ErrAliasLoop))
+	}))
+	t.Run("commands take precedence", x.F(func(x x.X) {
+		r := &result{}
+		c := newCmd(r, xli.UserAlias{Name: "checkout", Args: []string{"remote", "remove"}})
+		x.NoError(c.Run(t.Context(), []string{"checkout"}))
+		x.Equal("app checkout", r.cmd)
+	}))
+	t.Run("help", x.F(func(x x.X) {
+		b := &strings.Builder{}
+		c := newCmd(&result{}, xli.UserAlias{Name: "co", Args: []string{"checkout", "--quiet"}})
+		c.Writer = b
+		x.NoError(c.Run(t.Context(), []string{"--help"}))
+		x.Contains(b.String(), "  aliases:\n    co        = checkout --quiet\n")
+	}))
+	t.Run("help data is left to the tree", x.F(func(x x.X) {
+		c := newCmd(&result{}, xli.UserAlias{Name: "co", Args: []string{"checkout", "--quiet"}})
+		d := xli.NewHelpData(c)
+		for _, g := range d.Commands {
+			x.NotEqual("aliases", g.Category)
+		}
+	}))
+	t.Run("completion", x.F(func(x x.X) {
+		c := newCmd(&result{}, xli.UserAlias{Name: "co", Args: []string{"checkout", "--quiet"}})
+		out := complete(t, c, "", "")
+		x.Contains(out, "aliases\x1fco:= checkout --quiet\n")
+
+		// Words after an alias are completed as for the expansion.
+		c = newCmd(&result{}, xli.UserAlias{Name: "r", Args: []string{"remote"}})
+		out = complete(t, c, "", "", "r")
+		x.Contains(out, "remove")
+	}))
+	t.Run("manage", x.F(func(x x.X) {
+		b := &strings.Builder{}
+		c := newCmd(&result{})
+		c.Writer = b
+
+		x.NoError(c.Run(t.Context(), []string{"alias", "set", "co", "checkout --quiet"}))
+		x.NoError(c.Run(t.Context(), []string{"alias", "set", "remote rm", "remove 'a b'"}))
+		x.NoError(c.Run(t.Context(), []string{"alias", "list"}))
+		x.Equal("co = checkout --quiet\nremote rm = remove 'a b'\n", b.String())
+
+		err := c.Run(t.Context(), []string{"alias", "set", "checkout", "remote"})
+		x.ErrorContains(err, `"checkout" is a command`)
+		err = c.Run(t.Context(), []string{"alias", "set", "nope rm", "remote"})
+		x.True(errors.Is(err, xli.ErrUnknownCmd))
+
+		x.NoError(c.Run(t.Context(), []string{"alias", "rm", "co"}))
+		err = c.Run(t.Context(), []string{"alias", "rm", "co"})
+		x.ErrorContains(err, `alias "co" is not defined`)
+
+		out := complete(t, c, "", "", "alias", "rm")
+		x.Contains(out, "remote rm:remove 'a b'")
+	}))
+	t.Run("not enabled", x.F(func(x x.X) {
+		c := &xli.Command{Name: "app", Commands: xli.Commands{xli.NewCmdAlias()}}
+		err := c.Run(t.Context(), []string{"alias", "list"})
+		x.ErrorContains(err, "aliases are not enabled")
+	}))
+}
+
+func TestAliasFile(t *testing.T) {
+	t.Run("missing file", x.F(func(x x.X) {
+		vs, err := xli.AliasFile{Path: filepath.Join(t.TempDir(), "nope")}.Load()
+		x.NoError(err)
+		x.Len(vs, 0)
+	}))
+	t.Run("load", x.F(func(x x.X) {
+		p := filepath.Join(t.TempDir(), "aliases")
+		x.NoError(os.WriteFile(p, []byte("# comment\n\nco = checkout --quiet\nremote rm = remove 'a b'\n"), 0o644))
+
+		vs, err := xli.AliasFile{Path: p}.Load()
+		x.NoError(err)
+		x.Equal([]xli.UserAlias{
+			{Path: []string{}, Name: "co", Args: []string{"checkout", "--quiet"}},
+			{Path: []string{"remote"}, Name: "rm", Args: []string{"remove", "a b"}},
+		}, vs)
+	}))
+	t.Run("invalid", x.F(func(x x.X) {
+		p := filepath.Join(t.TempDir(), "aliases")
+		x.NoError(os.WriteFile(p, []byte("co checkout\n"), 0o644))
+
+		_, err := xli.AliasFile{Path: p}.Load()
+		x.ErrorContains(err, ":1: expected NAME = ARGS")
+	}))
+	t.Run("round trip", x.F(func(x x.X) {
+		f := xli.AliasFile{Path: filepath.Join(t.TempDir(), "dir", "aliases")}
+		vs := []xli.UserAlias{
+			{Path: []string{}, Name: "co", Args: []string{"checkout", "it's", "", "#x"}},
+		}
+		x.NoError(f.Save(vs))
+
+		ws, err := f.Load()
+		x.NoError(err)
+		x.Equal(vs, ws)
+	}))
+}
@@ -7,6 +7,7 @@ import (
 
 type Info struct {
 	Name string
+	Type string
 
 	Brief string
 	Synop string
@@ -26,6 +27,10 @@ type Arg interface {
 
 	IsOptional() bool
 	IsMany() bool
+
+	// Reset clears the value parsed from the command line, so the argument
+	// can be parsed again as if it was never given.
+	Reset()
 }
 
 type Args []Arg
@@ -49,6 +49,7 @@ func (a *Base[T, P]) Info() *Info {
 	}
 	info := &Info{
 		Name: a.Name,
+		Type: a.Parser.String(),
 
 		Brief: a.Brief,
 		Synop: a.Synop,
@@ -106,6 +107,18 @@ func (a *Base[T, P]) IsMany() bool {
 	return false
 }
 
+func (a *Base[T, P]) Reset() {
+	a.Value = nil
+}
+
+// Clone returns a copy of the argument without the parsed value, for
+// `xli.Command.Invoke` to run a copy of the command tree.
+func (a *Base[T, P]) Clone() Arg {
+	v := *a
+	v.Reset()
+	return &v
+}
+
 func (a *Base[T, P]) Parse(rest []string) (int, error) {
 	v, n, err := a.Parser.Parse(rest)
 	if n == 0 || err != nil {
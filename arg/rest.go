@@ -69,6 +69,7 @@ func (a *Rest[T, P]) Info() *Info {
 	}
 	info := &Info{
 		Name: a.Name,
+		Type: a.Parser.String(),
 
 		Brief: a.Brief,
 		Synop: a.Synop,
@@ -119,6 +120,18 @@ func (a *Rest[T, P]) IsMany() bool {
 	return true
 }
 
+func (a *Rest[T, P]) Reset() {
+	a.Value = nil
+}
+
+// Clone returns a copy of the argument without the parsed values, for
+// `xli.Command.Invoke` to run a copy of the command tree.
+func (a *Rest[T, P]) Clone() Arg {
+	v := *a
+	v.Reset()
+	return &v
+}
+
 func (a *Rest[T, P]) Parse(rest []string) (int, error) {
 	vs, n, err := a.Parser.Parse(rest)
 	if n == 0 || err != nil {
@@ -2,7 +2,6 @@ package xli
 
 import (
 	"context"
-	_ "embed"
 	"errors"
 	"fmt"
 	"io"
@@ -10,10 +9,12 @@ import (
 	"slices"
 	"strings"
 	"text/template"
+	"time"
 	"unicode/utf8"
 
 	"github.com/lesomnus/xli/arg"
 	"github.com/lesomnus/xli/flg"
+	"github.com/lesomnus/xli/frm"
 	"github.com/lesomnus/xli/lex"
 	"github.com/lesomnus/xli/mode"
 	"github.com/lesomnus/xli/tab"
@@ -26,6 +27,10 @@ type Command struct {
 	Brief    string
 	Synop    string
 	Usage    Stringer
+	Examples []Example
+
+	// Hidden commands can be run but are not listed in help or completion.
+	Hidden bool
 
 	Flags    flg.Flags
 	Args     arg.Args
@@ -33,11 +38,102 @@ type Command struct {
 
 	Handler Handler
 
+	// HelpTemplate renders the help message of this command and of its
+	// descendants that do not have their own; see `HelpData` for the data it
+	// is executed with. The nearest one up the tree is used, falling back to
+	// the built-in template if there is none.
+	HelpTemplate *template.Template
+	// Palette styles the help and error output of this command and of its
+	// descendants that do not have their own; `DefaultPalette` is used if
+	// there is none up the tree.
+	Palette *Palette
+	// Prompt sets whether to ask for missing required flags and arguments of
+	// this command and of its descendants that do not set their own.
+	Prompt PromptMode
+	// NoResponseFiles turns off the expansion of "@FILE" words given to `Run`;
+	// see `ExpandResponseFiles`.
+	NoResponseFiles bool
+	// UserAliases holds the aliases users define for subcommands, which
+	// `Run` expands; see `NewCmdAlias`.
+	UserAliases UserAliasStore
+	// Plugins resolves the subcommands of this command that are not in
+	// `Commands` into executables, which are run with the rest of the
+	// command line; see `PathPlugins`.
+	Plugins PluginResolver
+	// TabMatch decides which candidates complete the word being typed; the
+	// others are dropped before they reach the shell. `tab.MatchPrefix` is
+	// used if it is nil.
+	TabMatch tab.Match
+	// TabTimeout bounds the time a completion takes, so a slow handler does
+	// not hang the shell. The candidates emitted before it passes are
+	// written and the rest are dropped. `DefaultTabTimeout` is used if it is
+	// zero, and there is no bound if it is negative.
+	TabTimeout time.Duration
+
 	io.ReadCloser
 	io.Writer
 	ErrWriter io.Writer
 
 	parent *Command
+	exe    string // executable of a plugin
+
+	// IO fields set by `Run` rather than by the user, which `Reset` clears.
+	io_set ioFields
+}
+
+type ioFields uint8
+
+const (
+	ioReader ioFields = 1 << iota
+	ioWriter
+	ioErrWriter
+)
+
+// inheritIO sets the IO fields of `c` that are not set to the ones given.
+func (c *Command) inheritIO(r io.ReadCloser, w io.Writer, e io.Writer) {
+	if c.ReadCloser == nil {
+		c.ReadCloser = r
+		c.io_set |= ioReader
+	}
+	if c.Writer == nil {
+		c.Writer = w
+		c.io_set |= ioWriter
+	}
+	if c.ErrWriter == nil {
+		c.ErrWriter = e
+		c.io_set |= ioErrWriter
+	}
+}
+
+// Reset clears the state a previous `Run` left in `c` and its descendants:
+// the values of their flags and arguments and the IO they inherited, so the
+// tree can be run again. `Run` resets the tree before parsing, so calling it
+// is only needed to drop the values after a run.
+func (c *Command) Reset() {
+	for _, f := range c.Flags {
+		f.Reset()
+	}
+	for _, a := range c.Args {
+		a.Reset()
+	}
+	c.clearIO()
+	for _, v := range c.Commands {
+		v.Reset()
+	}
+}
+
+// clearIO clears the IO fields set by `Run`.
+func (c *Command) clearIO() {
+	if c.io_set&ioReader != 0 {
+		c.ReadCloser = nil
+	}
+	if c.io_set&ioWriter != 0 {
+		c.Writer = nil
+	}
+	if c.io_set&ioErrWriter != 0 {
+		c.ErrWriter = nil
+	}
+	c.io_set = 0
 }
 
 func (c *Command) GetName() string {
@@ -116,7 +212,16 @@ func (c *Command) Scanln(vs ...any) (int, error) {
 // It will not executes the subcommand if "--help" or "-h" is found in the execution command.
 // Handler has responsible to execute subcommand's handler.
 // This function does not guarantees execution of subcommand's handler.
+// The values left by a previous run are cleared first; see `Reset`.
+//
+// `NoResponseFiles`, `UserAliases`, `TabMatch`, and `TabTimeout` are read only
+// on the command Run is called on; those of its descendants are ignored.
+//
+// Run parses into the flags and arguments of `c` and its descendants, so it is
+// not safe for concurrent use; use `Invoke` to run the same tree concurrently.
 func (c *Command) Run(ctx context.Context, args []string) error {
+	c.Reset()
+
 	if l := len(args); l > 2 {
 		tag := args[l-3]
 		if sh, ok := strings.CutPrefix(tag, completion_tag_prefix); ok {
@@ -136,13 +241,63 @@ func (c *Command) Run(ctx context.Context, args []string) error {
 				return errors.New("unknown shell of completion")
 			}
 
-			ctx = tab.Into(ctx, t)
-			args = NormalizeCompletionArgs(args[:l-3], curr, buff)
-			return c.runCompletion(ctx, args)
+			args = args[:l-3]
+			if !c.NoResponseFiles {
+				if strings.HasPrefix(curr, "@") && !strings.HasPrefix(curr, "@@") {
+					completeResponseFile(t, curr)
+					return nil
+				}
+
+				vs, err := c.expandResponseFiles(args)
+				if err != nil {
+					// Nothing can be completed with a broken command line.
+					return nil
+				}
+				args = vs
+			}
+
+			timeout := c.TabTimeout
+			if timeout == 0 {
+				timeout = DefaultTabTimeout
+			}
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+
+			g := &tabGate{}
+			prefix := completionPrefix(curr, buff)
+			ctx = tab.Into(ctx, tab.Filter(&gatedTab{g: g, t: t}, prefix, c.TabMatch))
+			ctx = tab.PrefixInto(ctx, prefix, c.TabMatch)
+			norm := NormalizeCompletionArgs(slices.Clone(args), curr, buff)
+			if vs, err := c.expandUserAliases(norm); err == nil {
+				norm = vs
+			}
+			if c.completePlugin(ctx, w, args, norm, tag, curr, buff) {
+				return nil
+			}
+			return c.runCompletionUntil(ctx, norm, g)
+		}
+	}
+
+	if !c.NoResponseFiles {
+		vs, err := c.expandResponseFiles(args)
+		if err != nil {
+			return err
 		}
+		args = vs
 	}
 
-	f_root, err := parseFrameAll(c, args)
+	// Set ios if not set.
+	c.inheritIO(os.Stdin, os.Stdout, os.Stderr)
+
+	args, err := c.expandUserAliases(args)
+	if err != nil {
+		return err
+	}
+
+	f_root, err := c.parseFrameAllPrompt(ctx, args)
 	if err != nil {
 		return err
 	}
@@ -151,6 +306,10 @@ func (c *Command) Run(ctx context.Context, args []string) error {
 	// Parsed flags and args should be stored in each Arg and Flag.
 	for f := range f_root.Iter() {
 		if f.is_help {
+			// Flags given before "--help" may affect the help message (e.g.
+			// "--color"), so parse them on a best-effort basis; help must be
+			// printed even if they are invalid.
+			f.prepareFlags(ctx)
 			break
 		}
 		if err := f.prepare(ctx); err != nil {
@@ -158,6 +317,9 @@ func (c *Command) Run(ctx context.Context, args []string) error {
 		}
 	}
 
+	// Flags are handled with this context, before the mode is set.
+	ctx_prep := ctx
+
 	// Set a mode if not set.
 	if m := mode.From(ctx); m == mode.Unspecified {
 		m = mode.Run
@@ -176,31 +338,33 @@ func (c *Command) Run(ctx context.Context, args []string) error {
 	if mode.From(ctx).Is(mode.Run) {
 		for f := f_root; f != nil; f = f.next {
 			for _, fl := range f.c_curr.Flags {
-				if info := fl.Info(); info.Required && fl.Count() == 0 {
-					return fmt.Errorf("%w: --%s", ErrFlagRequired, info.Name)
+				info := fl.Info()
+				if !info.Required || fl.Count() > 0 {
+					continue
+				}
+
+				e := f.flagError(-1, lex.Flag("--"+info.Name), info, ErrFlagRequired)
+				p := c.prompter(f)
+				if p == nil {
+					return e
+				}
+				if err := p.promptFlag(ctx_prep, fl, e); err != nil {
+					return err
 				}
 			}
 		}
 	}
 
-	// Set ios if not set.
-	if c.ReadCloser == nil {
-		c.ReadCloser = os.Stdin
-	}
-	if c.Writer == nil {
-		c.Writer = os.Stdout
-	}
-	if c.ErrWriter == nil {
-		c.ErrWriter = os.Stderr
-	}
-
 	// Handlers are invoked sequentially.
 	return f_root.execute(ctx)
 }
 
 // completeCommands emits subcommand candidates, grouped by category.
 func completeCommands(t tab.Tab, c *Command) {
-	for _, group := range c.Commands.ByCategory() {
+	for _, group := range append(c.Commands.Visible().ByCategory(), c.userAliasCommands()) {
+		if len(group) == 0 {
+			continue
+		}
 		sink := t
 		if cat := group[0].Category; cat != "" {
 			sink = t.Group(cat)
@@ -209,10 +373,30 @@ func completeCommands(t tab.Tab, c *Command) {
 			sink.ValueD(v.Name, v.Brief)
 		}
 	}
+	completePlugins(t, c)
 }
 
-// completeFlagNames emits flag-name candidates, grouped by category.
-func completeFlagNames(t tab.Tab, c *Command) {
+// completeFlagNames emits flag-name candidates, grouped by category, and
+// "--help". Flags given in the frame `f` are left out unless they are
+// repeatable. Short names are offered as well unless `prefix`, the word being
+// completed, starts with "--".
+func completeFlagNames(t tab.Tab, f *frame, prefix string) {
+	c := f.c_curr
+	given := map[flg.Flag]bool{}
+	for _, v := range f.flags {
+		var h flg.Flag
+		if v.IsShort() {
+			r, _ := utf8.DecodeRuneInString(v.Name())
+			h = c.Flags.GetByAlias(r)
+		} else {
+			h = c.Flags.Get(v.Name())
+		}
+		if h != nil {
+			given[h] = true
+		}
+	}
+
+	short := !strings.HasPrefix(prefix, "--")
 	for _, group := range c.Flags.ByCategory() {
 		sink := t
 		if cat := group[0].Info().Category; cat != "" {
@@ -220,15 +404,26 @@ func completeFlagNames(t tab.Tab, c *Command) {
 		}
 		for _, u := range group {
 			v := u.Info()
+			if given[u] && !v.Repeatable {
+				continue
+			}
 			sink.ValueD(fmt.Sprintf("--%s", v.Name), v.Brief)
+			if short && v.Alias != 0 {
+				sink.ValueD(fmt.Sprintf("-%c", v.Alias), v.Brief)
+			}
 		}
 	}
+
+	t.ValueD("--help", "Show help")
+	if short {
+		t.ValueD("-h", "Show help")
+	}
 }
 
 // args must be a normalized one by `NormalizeCompletionArgs`.
 func (c *Command) runCompletion(ctx context.Context, args []string) error {
-	tab := tab.From(ctx)
-	if tab == nil {
+	t := tab.From(ctx)
+	if t == nil {
 		// Completion must never crash the user's shell; without a sink
 		// there is nothing to emit.
 		return nil
@@ -236,6 +431,12 @@ func (c *Command) runCompletion(ctx context.Context, args []string) error {
 
 	f_root, parse_err := parseFrameAll(c, args)
 	f_last := f_root.Last()
+	for f := range f_root.Iter() {
+		// Candidates may depend on the path, such as aliases and plugins.
+		if f.next != nil {
+			f.next.c_curr.parent = f.c_curr
+		}
+	}
 
 	need_val := false
 	need_arg := false
@@ -273,18 +474,21 @@ func (c *Command) runCompletion(ctx context.Context, args []string) error {
 
 	case strings.HasPrefix(last, "-"):
 		// "--" or "-": suggest flag names, grouped by category.
-		completeFlagNames(tab, c)
+		t.Directive(tab.NoFiles)
+		completeFlagNames(t, f_last, tab.Prefix(ctx))
 		return nil
 
 	default:
 		// Start of a (sub)command or a non-flag token: suggest subcommands,
 		// grouped by category.
-		completeCommands(tab, c)
+		t.Directive(tab.NoFiles)
+		completeCommands(t, c)
 		return nil
 	}
 
 	if f_root != f_last {
 		// Detach last frame.
+		f_last.c_curr.parent = f_last.prev.c_curr
 		f_last.prev.next = nil
 		f_last.prev = nil
 
@@ -302,6 +506,9 @@ func (c *Command) runCompletion(ctx context.Context, args []string) error {
 		}
 	}
 
+	// Handlers see the frame of the command being completed, so they can
+	// inspect the words given to it so far.
+	ctx = frm.Into(ctx, f_last)
 	ctx = mode.Into(ctx, mode.Tab)
 	if need_val {
 		f := lex.Flag(args[len(args)-1])
@@ -328,19 +535,6 @@ func (c *Command) runCompletion(ctx context.Context, args []string) error {
 	return nil
 }
 
-//go:embed help.go.tpl
-var DefaultHelpTemplate string
-
-// defaultHelpTemplate is parsed once at startup; the embedded template is a
-// compile-time constant, so a parse failure is a programmer error.
-var defaultHelpTemplate = template.Must(template.New("help").Parse(DefaultHelpTemplate))
-
-func (c *Command) PrintHelp(w io.Writer) error {
-	// TODO(Phase 4): allow a user-supplied template once the injection
-	// surface (Command field vs context) is decided.
-	return defaultHelpTemplate.Execute(w, c)
-}
-
 type Commands []*Command
 
 func (cs Commands) Get(name string) *Command {
@@ -356,6 +550,17 @@ func (cs Commands) Get(name string) *Command {
 	return nil
 }
 
+// Visible returns the commands that are not hidden.
+func (cs Commands) Visible() Commands {
+	vs := Commands{}
+	for _, c := range cs {
+		if !c.Hidden {
+			vs = append(vs, c)
+		}
+	}
+	return vs
+}
+
 func (cs Commands) ByCategory() []Commands {
 	i := map[string]int{}
 	vs := []Commands{}
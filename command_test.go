@@ -3,6 +3,7 @@ package xli_test
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 
 	"github.com/lesomnus/xli"
@@ -141,3 +142,82 @@ func TestCommandExecutionOrder(t *testing.T) {
 		x.Equal([]string{"foo"}, vs)
 	}))
 }
+
+func TestCommandRerun(t *testing.T) {
+	type result struct {
+		name    string
+		name_ok bool
+		verbose bool
+		files   []string
+	}
+	newCmd := func(r *result) *xli.Command {
+		anon := "anon"
+		return &xli.Command{
+			Name: "app",
+			Flags: flg.Flags{
+				&flg.String{Name: "name", Default: &anon},
+				&flg.Switch{Name: "verbose"},
+			},
+			Commands: xli.Commands{
+				&xli.Command{
+					Name: "add",
+					Args: arg.Args{&arg.RestStrings{Name: "FILES"}},
+					Handler: xli.OnRun(func(ctx context.Context, cmd *xli.Command, next xli.Next) error {
+						p := cmd.Parent()
+						r.name = flg.MustGet[string](p, "name")
+						_, r.name_ok = flg.Get[string](p, "name")
+						r.verbose, _ = flg.Get[bool](p, "verbose")
+						r.files, _ = arg.Get[[]string](cmd, "FILES")
+						cmd.Println(len(r.files))
+						return next(ctx)
+					}),
+				},
+			},
+		}
+	}
+
+	t.Run("values of previous run are cleared", x.F(func(x x.X) {
+		r := &result{}
+		c := newCmd(r)
+
+		err := c.Run(t.Context(), []string{"--name=foo", "--verbose", "add", "a", "b"})
+		x.NoError(err)
+		x.Equal(result{name: "foo", name_ok: true, verbose: true, files: []string{"a", "b"}}, *r)
+
+		err = c.Run(t.Context(), []string{"add"})
+		x.NoError(err)
+		x.Equal(result{name: "anon"}, *r)
+	}))
+	t.Run("inherited IO is cleared", x.F(func(x x.X) {
+		c := newCmd(&result{})
+
+		o1 := &strings.Builder{}
+		c.Writer = o1
+		err := c.Run(t.Context(), []string{"add", "a"})
+		x.NoError(err)
+
+		o2 := &strings.Builder{}
+		c.Writer = o2
+		err = c.Run(t.Context(), []string{"add", "a", "b"})
+		x.NoError(err)
+
+		x.Equal("1\n", o1.String())
+		x.Equal("2\n", o2.String())
+	}))
+	t.Run("IO set by the user is kept", x.F(func(x x.X) {
+		c := newCmd(&result{})
+		o := &strings.Builder{}
+		c.Commands[0].Writer = o
+		c.Writer = &strings.Builder{}
+
+		err := c.Run(t.Context(), []string{"add", "a"})
+		x.NoError(err)
+		c.Reset()
+		x.Same(o, c.Commands[0].Writer)
+		x.Nil(c.Commands[0].ErrWriter)
+
+		v, ok := flg.Get[string](c, "name")
+		x.False(ok)
+		x.Equal("", v)
+	}))
+}
@@ -4,10 +4,18 @@ import (
 	"context"
 	"embed"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/lesomnus/xli/tab"
 )
 
 const completion_tag_prefix = "$$xli_completion_"
 
+// DefaultTabTimeout is the time a completion may take if `Command.TabTimeout`
+// is not set.
+const DefaultTabTimeout = 2 * time.Second
+
 //go:embed completions
 var completions embed.FS
 
@@ -98,3 +106,89 @@ func NormalizeCompletionArgs(args []string, curr string, buff string) []string {
 
 	return args
 }
+
+// completionPrefix returns the part of `curr` on the left of the cursor, given
+// `buff` as `NormalizeCompletionArgs` is, without the "--flag=" of a flag
+// value.
+func completionPrefix(curr string, buff string) string {
+	p := ""
+	for i := 0; i <= len(buff); i++ {
+		if strings.HasPrefix(curr, buff[i:]) {
+			p = buff[i:]
+			break
+		}
+	}
+	if strings.HasPrefix(p, "-") {
+		if _, v, ok := strings.Cut(p, "="); ok {
+			return v
+		}
+	}
+	return p
+}
+
+// runCompletionUntil runs `runCompletion` until the deadline of `ctx`, if
+// any. At the deadline, `g` is closed so the candidates emitted so far are
+// kept and a handler that is still running writes nothing more. The handler
+// is left running on a copy of the tree, so `c` can be run again meanwhile.
+func (c *Command) runCompletionUntil(ctx context.Context, args []string, g *tabGate) error {
+	if _, ok := ctx.Deadline(); !ok {
+		return c.runCompletion(ctx, args)
+	}
+
+	v := c.clone()
+	v.parent = c.parent
+	done := make(chan error, 1)
+	go func() {
+		done <- v.runCompletion(ctx, args)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		g.close()
+		return nil
+	}
+}
+
+// tabGate is shared by the `gatedTab`s of a completion.
+type tabGate struct {
+	mu     sync.Mutex
+	closed bool
+}
+
+func (g *tabGate) close() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.closed = true
+}
+
+// do runs `f` unless the gate is closed.
+func (g *tabGate) do(f func()) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.closed {
+		f()
+	}
+}
+
+// gatedTab passes candidates to `t` until its gate is closed.
+type gatedTab struct {
+	g *tabGate
+	t tab.Tab
+}
+
+func (t *gatedTab) Value(v string) {
+	t.g.do(func() { t.t.Value(v) })
+}
+
+func (t *gatedTab) ValueD(v string, desc string) {
+	t.g.do(func() { t.t.ValueD(v, desc) })
+}
+
+func (t *gatedTab) Group(name string) tab.Tab {
+	return &gatedTab{g: t.g, t: t.t.Group(name)}
+}
+
+func (t *gatedTab) Directive(d tab.Directive) {
+	t.g.do(func() { t.t.Directive(d) })
+}
@@ -0,0 +1,209 @@
+package xli
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lesomnus/xli/frm"
+	"github.com/lesomnus/xli/tab"
+)
+
+// TabCache keeps the candidates a completion handler emits in files, so a
+// slow source is not queried on every completion. An entry is kept for the
+// path of the command being completed, `Name`, and the word being completed.
+//
+//	Handler: flg.OnTab[string](func(ctx context.Context, t tab.Tab) error {
+//		c := xli.TabCache{Name: "region", TTL: time.Hour}
+//		return c.Complete(ctx, t, listRegions)
+//	}),
+type TabCache struct {
+	// Name tells apart the caches of a command, e.g. the name of the flag
+	// whose values are completed.
+	Name string
+	// TTL is how long an entry is used for.
+	TTL time.Duration
+	// Dir holds the entries. It is "<user cache dir>/<root>/completion" if
+	// empty.
+	Dir string
+}
+
+// Complete emits into `t` the candidates `f` emitted for the same entry within
+// the TTL, or runs `f` with `t` and keeps what it emits. Nothing is kept if
+// `f` fails or the deadline of `ctx` passes, as the candidates may be
+// partial. The cache is best effort; `f` is run if an entry cannot be read.
+func (c TabCache) Complete(ctx context.Context, t tab.Tab, f func(ctx context.Context, t tab.Tab) error) error {
+	p, ok := c.path(ctx)
+	if !ok {
+		return f(ctx, t)
+	}
+	if s, ok := c.load(p); ok {
+		replayTab(t, s)
+		return nil
+	}
+
+	b := &bytes.Buffer{}
+	if err := f(ctx, &teeTab{t, tab.NewZshTab(b)}); err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	c.save(p, b.Bytes())
+	return nil
+}
+
+// path returns the file of the entry for the completion in `ctx`.
+func (c TabCache) path(ctx context.Context) (string, bool) {
+	f := frm.From(ctx)
+	if f == nil {
+		return "", false
+	}
+	cmd, ok := f.Cmd().(*Command)
+	if !ok {
+		return "", false
+	}
+
+	names := []string{}
+	for _, v := range cmd.Tree() {
+		names = append(names, v.Name)
+	}
+
+	dir := c.Dir
+	if dir == "" {
+		d, err := os.UserCacheDir()
+		if err != nil {
+			return "", false
+		}
+		dir = filepath.Join(d, names[0], "completion")
+	}
+
+	key := strings.Join(append(names, c.Name, tab.Prefix(ctx)), "\x00")
+	h := sha256.Sum256([]byte(key))
+	return filepath.Join(dir, hex.EncodeToString(h[:])), true
+}
+
+// TabCacheMaxAge is how long an entry is kept in the directory at least; those
+// older than it and than their TTL are swept. An entry whose TTL is longer
+// than that of the cache that sweeps may be swept early, which only costs
+// running its handler again.
+const TabCacheMaxAge = 24 * time.Hour
+
+// tabCacheSweepEvery is how often the directory is swept.
+const tabCacheSweepEvery = time.Hour
+
+func (c TabCache) load(p string) (string, bool) {
+	info, err := os.Stat(p)
+	if err != nil {
+		return "", false
+	}
+	if time.Since(info.ModTime()) > c.TTL {
+		os.Remove(p)
+		return "", false
+	}
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return "", false
+	}
+	return string(b), true
+}
+
+// save writes the entry to a temporary file first, so a completion running
+// at the same time never reads a partial one.
+func (c TabCache) save(p string, b []byte) {
+	if err := os.MkdirAll(filepath.Dir(p), 0o700); err != nil {
+		return
+	}
+	f, err := os.CreateTemp(filepath.Dir(p), ".tmp-*")
+	if err != nil {
+		return
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return
+	}
+	if err := os.Rename(f.Name(), p); err != nil {
+		os.Remove(f.Name())
+	}
+	c.sweep(filepath.Dir(p))
+}
+
+// sweep removes the stale entries in `dir`, and temporary files left by
+// completions that were killed, at most once per `tabCacheSweepEvery`. The time
+// of the last sweep is kept as the modification time of a ".swept" file.
+func (c TabCache) sweep(dir string) {
+	mark := filepath.Join(dir, ".swept")
+	if info, err := os.Stat(mark); err == nil && time.Since(info.ModTime()) < tabCacheSweepEvery {
+		return
+	}
+	if err := os.WriteFile(mark, nil, 0o600); err != nil {
+		return
+	}
+
+	es, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	age := max(c.TTL, TabCacheMaxAge)
+	for _, e := range es {
+		if e.IsDir() || e.Name() == ".swept" {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || time.Since(info.ModTime()) <= age {
+			continue
+		}
+		os.Remove(filepath.Join(dir, e.Name()))
+	}
+}
+
+// replayTab emits into `t` the candidates and directives in `s`, the output
+// of a `tab.ZshTab`.
+func replayTab(t tab.Tab, s string) {
+	if d := tab.ParseZshDirective(s); d != 0 {
+		t.Directive(d)
+	}
+	for _, v := range tab.ParseZsh(s) {
+		u := t
+		if v.Group != "" {
+			u = t.Group(v.Group)
+		}
+		if v.Desc == "" {
+			u.Value(v.Value)
+		} else {
+			u.ValueD(v.Value, v.Desc)
+		}
+	}
+}
+
+// teeTab passes candidates to both of its Tabs.
+type teeTab [2]tab.Tab
+
+func (t *teeTab) Value(v string) {
+	t[0].Value(v)
+	t[1].Value(v)
+}
+
+func (t *teeTab) ValueD(v string, desc string) {
+	t[0].ValueD(v, desc)
+	t[1].ValueD(v, desc)
+}
+
+func (t *teeTab) Group(name string) tab.Tab {
+	return &teeTab{t[0].Group(name), t[1].Group(name)}
+}
+
+func (t *teeTab) Directive(d tab.Directive) {
+	t[0].Directive(d)
+	t[1].Directive(d)
+}
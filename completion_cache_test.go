@@ -0,0 +1,165 @@
+package xli_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lesomnus/xli"
+	"github.com/lesomnus/xli/arg"
+	"github.com/lesomnus/xli/flg"
+	"github.com/lesomnus/xli/internal/x"
+	"github.com/lesomnus/xli/tab"
+)
+
+func TestTabCache(t *testing.T) {
+	type source struct {
+		n   int
+		err error
+	}
+	newCmd := func(dir string, s *source) *xli.Command {
+		cache := xli.TabCache{Name: "region", TTL: time.Hour, Dir: dir}
+		return &xli.Command{
+			Name: "app",
+			Commands: xli.Commands{
+				&xli.Command{
+					Name: "deploy",
+					Flags: flg.Flags{
+						&flg.String{Name: "region", Handler: flg.OnTab[string](func(ctx context.Context, t tab.Tab) error {
+							return cache.Complete(ctx, t, func(ctx context.Context, t tab.Tab) error {
+								s.n++
+								t.Directive(tab.KeepOrder)
+								t.ValueD("us-east", "Virginia")
+								t.Group("eu").Value("eu-west")
+								return s.err
+							})
+						})},
+					},
+				},
+			},
+		}
+	}
+
+	t.Run("replayed within the TTL", x.F(func(x x.X) {
+		dir := t.TempDir()
+		s := &source{}
+		out := complete(t, newCmd(dir, s), "--region=", "--region=", "deploy", "--region=")
+		x.Equal(1, s.n)
+
+		again := complete(t, newCmd(dir, s), "--region=", "--region=", "deploy", "--region=")
+		x.Equal(1, s.n)
+		x.Equal(out, again)
+		x.Equal(tab.KeepOrder, tab.ParseZshDirective(again))
+		x.Equal([]tab.Candidate{
+			{Value: "us-east", Desc: "Virginia"},
+			{Group: "eu", Value: "eu-west"},
+		}, tab.ParseZsh(again))
+	}))
+	t.Run("keyed by the word", x.F(func(x x.X) {
+		dir := t.TempDir()
+		s := &source{}
+		complete(t, newCmd(dir, s), "--region=", "--region=", "deploy", "--region=")
+		out := complete(t, newCmd(dir, s), "--region=u", "--region=u", "deploy", "--region=u")
+		x.Equal(2, s.n)
+		x.Equal([]tab.Candidate{{Value: "us-east", Desc: "Virginia"}}, tab.ParseZsh(out))
+	}))
+	entries := func(x x.X, dir string) []string {
+		es, err := os.ReadDir(dir)
+		x.NoError(err)
+		vs := []string{}
+		for _, e := range es {
+			if !strings.HasPrefix(e.Name(), ".") {
+				vs = append(vs, e.Name())
+			}
+		}
+		return vs
+	}
+	t.Run("expired", x.F(func(x x.X) {
+		dir := t.TempDir()
+		s := &source{}
+		complete(t, newCmd(dir, s), "", "", "deploy", "--region")
+
+		es := entries(x, dir)
+		x.Len(es, 1)
+		old := time.Now().Add(-2 * time.Hour)
+		x.NoError(os.Chtimes(filepath.Join(dir, es[0]), old, old))
+
+		complete(t, newCmd(dir, s), "", "", "deploy", "--region")
+		x.Equal(2, s.n)
+		x.Len(entries(x, dir), 1)
+	}))
+	t.Run("expired entry is removed", x.F(func(x x.X) {
+		dir := t.TempDir()
+		complete(t, newCmd(dir, &source{}), "", "", "deploy", "--region")
+
+		es := entries(x, dir)
+		x.Len(es, 1)
+		old := time.Now().Add(-2 * time.Hour)
+		x.NoError(os.Chtimes(filepath.Join(dir, es[0]), old, old))
+
+		// Nothing is saved on error, so only the load removes the entry.
+		s := &source{err: errors.New("unavailable")}
+		complete(t, newCmd(dir, s), "", "", "deploy", "--region")
+		x.Empty(entries(x, dir))
+	}))
+	t.Run("stale entries are swept", x.F(func(x x.X) {
+		dir := t.TempDir()
+		old := time.Now().Add(-2 * xli.TabCacheMaxAge)
+		for _, name := range []string{"stale", ".tmp-123"} {
+			p := filepath.Join(dir, name)
+			x.NoError(os.WriteFile(p, nil, 0o600))
+			x.NoError(os.Chtimes(p, old, old))
+		}
+
+		complete(t, newCmd(dir, &source{}), "", "", "deploy", "--region")
+		_, err := os.Stat(filepath.Join(dir, "stale"))
+		x.True(errors.Is(err, os.ErrNotExist))
+		_, err = os.Stat(filepath.Join(dir, ".tmp-123"))
+		x.True(errors.Is(err, os.ErrNotExist))
+		x.Len(entries(x, dir), 1)
+	}))
+	t.Run("not kept on error", x.F(func(x x.X) {
+		dir := t.TempDir()
+		s := &source{err: errors.New("unavailable")}
+		complete(t, newCmd(dir, s), "", "", "deploy", "--region")
+		complete(t, newCmd(dir, s), "", "", "deploy", "--region")
+		x.Equal(2, s.n)
+	}))
+	t.Run("without a frame", x.F(func(x x.X) {
+		n := 0
+		c := xli.TabCache{TTL: time.Hour, Dir: t.TempDir()}
+		z := tab.NewZshTab(io.Discard)
+		for range 2 {
+			err := c.Complete(t.Context(), z, func(ctx context.Context, t tab.Tab) error {
+				n++
+				return nil
+			})
+			x.NoError(err)
+		}
+		x.Equal(2, n)
+	}))
+	t.Run("argument values", x.F(func(x x.X) {
+		dir := t.TempDir()
+		n := 0
+		cache := xli.TabCache{Name: "NAME", TTL: time.Hour, Dir: dir}
+		c := &xli.Command{
+			Name: "app",
+			Args: arg.Args{&arg.String{Name: "NAME", Handler: arg.OnTab[string](func(ctx context.Context, t tab.Tab) {
+				cache.Complete(ctx, t, func(ctx context.Context, t tab.Tab) error {
+					n++
+					t.Value("foo")
+					return nil
+				})
+			})}},
+		}
+		complete(t, c, "", "")
+		out := complete(t, c, "", "")
+		x.Equal(1, n)
+		x.Equal("\x1ffoo\n", out)
+	}))
+}
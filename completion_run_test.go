@@ -4,10 +4,12 @@ import (
 	"context"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/lesomnus/xli"
 	"github.com/lesomnus/xli/arg"
 	"github.com/lesomnus/xli/flg"
+	"github.com/lesomnus/xli/frm"
 	"github.com/lesomnus/xli/internal/x"
 	"github.com/lesomnus/xli/tab"
 )
@@ -125,6 +127,213 @@ func TestCompletionRun(t *testing.T) {
 	}))
 }
 
+func TestCompletionPrefix(t *testing.T) {
+	t.Run("subcommands", x.F(func(x x.X) {
+		out := complete(t, newCompletionTestCmd(), "ec", "ec", "ec")
+		x.Contains(out, "echo")
+		x.NotContains(out, "ping")
+	}))
+	t.Run("flag names", x.F(func(x x.X) {
+		c := newCompletionTestCmd()
+		c.Flags = append(c.Flags, &flg.String{Name: "baz"}, &flg.String{Name: "qux"})
+		out := complete(t, c, "--ba", "--ba", "--ba")
+		x.Contains(out, "--bar")
+		x.Contains(out, "--baz")
+		x.NotContains(out, "--qux")
+	}))
+	t.Run("flag value after =", x.F(func(x x.X) {
+		var prefix string
+		c := &xli.Command{
+			Name: "app",
+			Flags: flg.Flags{
+				&flg.String{Name: "bar", Handler: flg.OnTab[string](func(ctx context.Context, t tab.Tab) error {
+					prefix = tab.Prefix(ctx)
+					t.Value("foo")
+					t.Value("bar")
+					return nil
+				})},
+			},
+		}
+		out := complete(t, c, "--bar=f", "--bar=f", "--bar=f")
+		x.Equal("f", prefix)
+		x.Contains(out, "foo")
+		x.NotContains(out, "\x1fbar")
+	}))
+	t.Run("argument value", x.F(func(x x.X) {
+		var prefix string
+		c := &xli.Command{
+			Name: "app",
+			Args: arg.Args{
+				&arg.String{Name: "NAME", Handler: arg.OnTab[string](func(ctx context.Context, t tab.Tab) {
+					prefix = tab.Prefix(ctx)
+					for _, v := range []string{"alpha", "beta"} {
+						// Handlers can skip candidates that do not match.
+						if tab.Matches(ctx, v) {
+							t.Value(v)
+						}
+					}
+				})},
+			},
+		}
+		out := complete(t, c, "be", "be", "be")
+		x.Equal("be", prefix)
+		x.Equal("\x1fbeta\n", out)
+	}))
+	t.Run("cursor in the middle of the word", x.F(func(x x.X) {
+		// "ec|ho": the word is "echo" but only "ec" is on the left.
+		out := complete(t, newCompletionTestCmd(), "echo", "p ec", "echo")
+		x.Contains(out, "echo")
+		x.NotContains(out, "ping")
+	}))
+	t.Run("fuzzy", x.F(func(x x.X) {
+		c := newCompletionTestCmd()
+		c.TabMatch = tab.MatchFuzzy
+		out := complete(t, c, "eh", "eh", "eh")
+		x.Contains(out, "echo")
+		x.NotContains(out, "ping")
+	}))
+}
+
+type repeatedParser struct{ flg.StringParser }
+
+func (repeatedParser) IsRepeatable() bool { return true }
+
+func TestCompletionFlagNames(t *testing.T) {
+	newCmd := func() *xli.Command {
+		return &xli.Command{
+			Name: "app",
+			Flags: flg.Flags{
+				&flg.String{Name: "bar", Alias: 'b', Brief: "bar-brief"},
+				&flg.Switch{Name: "quiet", Alias: 'q'},
+				&flg.Base[string, repeatedParser]{Name: "tag"},
+			},
+		}
+	}
+
+	t.Run("long names after --", x.F(func(x x.X) {
+		cs := tab.ParseZsh(complete(t, newCmd(), "--", "--", "--"))
+		x.Equal([]tab.Candidate{
+			{Value: "--bar", Desc: "bar-brief"},
+			{Value: "--quiet"},
+			{Value: "--tag"},
+			{Value: "--help", Desc: "Show help"},
+		}, cs)
+	}))
+	t.Run("short names after -", x.F(func(x x.X) {
+		cs := tab.ParseZsh(complete(t, newCmd(), "-", "-", "-"))
+		x.Equal([]tab.Candidate{
+			{Value: "--bar", Desc: "bar-brief"},
+			{Value: "-b", Desc: "bar-brief"},
+			{Value: "--quiet"},
+			{Value: "-q"},
+			{Value: "--tag"},
+			{Value: "--help", Desc: "Show help"},
+			{Value: "-h", Desc: "Show help"},
+		}, cs)
+	}))
+	t.Run("given flags are left out unless repeatable", x.F(func(x x.X) {
+		vs := []string{}
+		for _, c := range tab.ParseZsh(complete(t, newCmd(), "--", "--", "-b", "x", "--quiet", "--tag=a", "--")) {
+			vs = append(vs, c.Value)
+		}
+		x.Equal([]string{"--tag", "--help"}, vs)
+	}))
+}
+
+func TestCompletionDirective(t *testing.T) {
+	t.Run("no file fallback for subcommands and flag names", x.F(func(x x.X) {
+		out := complete(t, newCompletionTestCmd(), "", "")
+		x.Equal(tab.NoFiles, tab.ParseZshDirective(out))
+
+		out = complete(t, newCompletionTestCmd(), "--", "--", "--")
+		x.Equal(tab.NoFiles, tab.ParseZshDirective(out))
+	}))
+	t.Run("values fall back to files", x.F(func(x x.X) {
+		c := &xli.Command{
+			Name: "app",
+			Args: arg.Args{&arg.String{Name: "FILE"}},
+		}
+		out := complete(t, c, "", "")
+		x.Equal(tab.Directive(0), tab.ParseZshDirective(out))
+	}))
+	t.Run("from handlers", x.F(func(x x.X) {
+		c := &xli.Command{
+			Name: "app",
+			Flags: flg.Flags{
+				&flg.String{Name: "set", Handler: flg.OnTab[string](func(ctx context.Context, t tab.Tab) error {
+					t.Group("keys").Directive(tab.NoSpace | tab.KeepOrder)
+					t.Value("b=")
+					t.Value("a=")
+					return nil
+				})},
+			},
+		}
+		out := complete(t, c, "--set=", "--set=", "--set=")
+		x.Equal(tab.NoSpace|tab.KeepOrder, tab.ParseZshDirective(out))
+		x.Equal([]tab.Candidate{{Value: "b="}, {Value: "a="}}, tab.ParseZsh(out))
+	}))
+}
+
+func TestCompletionTimeout(t *testing.T) {
+	t.Run("handlers see the deadline", x.F(func(x x.X) {
+		ok := false
+		c := &xli.Command{
+			Name: "app",
+			Args: arg.Args{&arg.String{Name: "NAME", Handler: arg.OnTab[string](func(ctx context.Context, t tab.Tab) {
+				_, ok = ctx.Deadline()
+			})}},
+		}
+		complete(t, c, "", "")
+		x.True(ok)
+
+		c.TabTimeout = -1
+		complete(t, c, "", "")
+		x.False(ok)
+	}))
+	t.Run("partial results are written at the deadline", x.F(func(x x.X) {
+		block := make(chan struct{})
+		t.Cleanup(func() { close(block) })
+
+		c := &xli.Command{
+			Name:       "app",
+			TabTimeout: 20 * time.Millisecond,
+			Args: arg.Args{&arg.String{Name: "NAME", Handler: arg.OnTab[string](func(ctx context.Context, t tab.Tab) {
+				t.Value("fast")
+				// A source that ignores the context.
+				<-block
+				t.Value("slow")
+			})}},
+		}
+		out := complete(t, c, "", "")
+		x.Equal("\x1ffast\n", out)
+	}))
+	t.Run("tree can be run again while a handler is still running", x.F(func(x x.X) {
+		block := make(chan struct{})
+		done := make(chan string)
+
+		c := &xli.Command{
+			Name:       "app",
+			TabTimeout: 20 * time.Millisecond,
+			Flags:      flg.Flags{&flg.String{Name: "foo"}},
+			Commands: xli.Commands{&xli.Command{
+				Name: "sub",
+				Args: arg.Args{&arg.String{Name: "NAME", Handler: arg.OnTab[string](func(ctx context.Context, t tab.Tab) {
+					<-block
+					cmd := frm.From(ctx).Cmd().(*xli.Command)
+					v, _ := flg.Get[string](cmd.Parent(), "foo")
+					done <- v
+				})}},
+			}},
+		}
+		complete(t, c, "", "", "--foo=a", "sub")
+
+		close(block)
+		err := c.Run(t.Context(), []string{"--foo=b", "sub", "bar"})
+		x.NoError(err)
+		x.Equal("a", <-done)
+	}))
+}
+
 func TestCompletionScript(t *testing.T) {
 	t.Run("zsh script is keyed on the root command name", x.F(func(x x.X) {
 		c := &xli.Command{
@@ -140,4 +349,35 @@ func TestCompletionScript(t *testing.T) {
 		x.NoError(err)
 		x.Contains(b.String(), "#compdef app")
 	}))
+	t.Run("zsh script honors the directives", x.F(func(x x.X) {
+		c := &xli.Command{
+			Name: "app",
+			Commands: xli.Commands{
+				xli.NewCmdCompletion(),
+			},
+		}
+
+		b := &strings.Builder{}
+		c.Writer = b
+		err := c.Run(context.Background(), []string{"completion", "zsh"})
+		x.NoError(err)
+		for _, d := range []tab.Directive{tab.NoSpace, tab.NoFiles, tab.KeepOrder, tab.Dirs} {
+			x.Contains(b.String(), "[(Ie)"+d.String()+"]")
+		}
+	}))
+	t.Run("zsh script does not filter the candidates again", x.F(func(x x.X) {
+		c := &xli.Command{
+			Name: "app",
+			Commands: xli.Commands{
+				xli.NewCmdCompletion(),
+			},
+		}
+
+		b := &strings.Builder{}
+		c.Writer = b
+		err := c.Run(context.Background(), []string{"completion", "zsh"})
+		x.NoError(err)
+		x.Contains(b.String(), "copts=(-U)")
+		x.Contains(b.String(), `_describe "${dopts[@]}" 'values' opts "${copts[@]}"`)
+	}))
 }
@@ -3,42 +3,198 @@ package xli
 import (
 	"errors"
 	"fmt"
+	"strings"
+	"unicode/utf8"
 
+	"github.com/lesomnus/xli/arg"
+	"github.com/lesomnus/xli/flg"
 	"github.com/lesomnus/xli/lex"
 )
 
 var (
 	ErrUnknownFlag  = errors.New("unknown flag")
 	ErrNoFlagValue  = errors.New("no value is given")
+	ErrInvalidFlag  = errors.New("invalid flag value")
 	ErrFlagRequired = errors.New("required flag not set")
 	ErrFlagAfterArg = errors.New("flag must come before arguments")
 	ErrUnknownCmd   = errors.New("unknown subcommand")
 	ErrTooManyArgs  = errors.New("too many arguments")
 	ErrNeedArgs     = errors.New("required argument not given")
+	ErrInvalidArg   = errors.New("invalid argument")
 )
 
+// ParseError is implemented by the errors `Run` returns for a command line
+// that does not fit the command tree; use `errors.As` to retrieve it.
+type ParseError interface {
+	error
+
+	// Path returns the names of the commands from the root to the command
+	// whose flags or args were being parsed.
+	Path() []string
+	// Args returns the command line as it was parsed: the argv given to
+	// `Run` with response files and aliases expanded and prompted arguments
+	// inserted. The values given to secret flags are replaced by
+	// "[redacted]", unless they refer to a file such as "@FILE".
+	Args() []string
+	// Index returns the index of the offending word in `Args`. It is
+	// `len(Args())` when a word is missing at the end of the command line
+	// and -1 when the error is not tied to any position.
+	Index() int
+}
+
+// FlagError reports a problem with a flag of a command.
 type FlagError struct {
-	flag lex.Flag
-	err  error
+	path  []string
+	args  []string
+	index int
+	flag  lex.Flag
+	info  *flg.Info
+	err   error
+	cause error
 }
 
 func (e *FlagError) Error() string {
-	return fmt.Sprintf("%s: %s", e.flag.WithoutArg().Raw(), e.err.Error())
+	if e.cause == nil {
+		return fmt.Sprintf("%s: %s", e.flag.WithoutArg().Raw(), e.err.Error())
+	}
+	return fmt.Sprintf("%s: %s: %s", e.Flag().String(), e.err.Error(), e.cause.Error())
+}
+
+func (e *FlagError) isSecret() bool {
+	return e.info != nil && e.info.Secret
+}
+
+// Unwrap returns the kind of the error, such as `ErrUnknownFlag`, and the
+// error reported by the flag's parser if any.
+// `errors.Unwrap` returns nil for it; use `errors.Is` or `errors.As`.
+func (e *FlagError) Unwrap() []error {
+	if e.cause == nil {
+		return []error{e.err}
+	}
+	return []error{e.err, e.cause}
+}
+
+func (e *FlagError) Path() []string {
+	return e.path
+}
+
+func (e *FlagError) Args() []string {
+	return e.args
+}
+
+func (e *FlagError) Index() int {
+	return e.index
+}
+
+// Flag returns the flag token as it was given, with its value attached if any
+// and the flag is not a secret.
+func (e *FlagError) Flag() lex.Flag {
+	if e.isSecret() {
+		return e.flag.WithoutArg()
+	}
+	return e.flag
+}
+
+// Info returns the information of the flag, or nil if the flag is unknown.
+func (e *FlagError) Info() *flg.Info {
+	return e.info
 }
 
-func (e *FlagError) Unwrap() error {
-	return e.err
+// Value returns the raw value given to the flag. It is empty for a secret.
+func (e *FlagError) Value() (string, bool) {
+	v, ok := e.flag.Arg()
+	if e.isSecret() {
+		return "", ok
+	}
+	return v.Raw(), ok
 }
 
+// Cause returns the error reported by the flag's parser, or nil.
+func (e *FlagError) Cause() error {
+	return e.cause
+}
+
+// ArgError reports a problem with a positional argument or a subcommand name.
 type ArgError struct {
-	arg lex.Arg
-	err error
+	path  []string
+	args  []string
+	index int
+	arg   lex.Arg
+	info  *arg.Info
+	err   error
+	cause error
 }
 
 func (e *ArgError) Error() string {
-	return fmt.Sprintf("%s: %s", e.arg.Raw(), e.err.Error())
+	s := e.arg.Raw()
+	if e.info != nil && errors.Is(e.err, ErrNeedArgs) {
+		s = fmt.Sprintf("%q", e.info.Name)
+	}
+	if e.cause == nil {
+		return fmt.Sprintf("%s: %s", s, e.err.Error())
+	}
+	return fmt.Sprintf("%s: %s: %s", s, e.err.Error(), e.cause.Error())
 }
 
-func (e *ArgError) Unwrap() error {
-	return e.err
+// Unwrap returns the kind of the error, such as `ErrTooManyArgs`, and the
+// error reported by the argument's parser if any.
+// `errors.Unwrap` returns nil for it; use `errors.Is` or `errors.As`.
+func (e *ArgError) Unwrap() []error {
+	if e.cause == nil {
+		return []error{e.err}
+	}
+	return []error{e.err, e.cause}
+}
+
+func (e *ArgError) Path() []string {
+	return e.path
+}
+
+func (e *ArgError) Args() []string {
+	return e.args
+}
+
+func (e *ArgError) Index() int {
+	return e.index
+}
+
+// Info returns the information of the argument, or nil if the word does not
+// correspond to any argument (e.g. an unknown subcommand).
+func (e *ArgError) Info() *arg.Info {
+	return e.info
+}
+
+// Value returns the raw word given; it is empty if the argument is missing.
+func (e *ArgError) Value() string {
+	return e.arg.Raw()
+}
+
+// Cause returns the error reported by the argument's parser, or nil.
+func (e *ArgError) Cause() error {
+	return e.cause
+}
+
+// Caret renders `args` on a line followed by a line with a caret under the
+// word at index `i`, for pointing at the position reported by `ParseError`:
+//
+//	app --port=abc serve
+//	    ^
+//
+// Words are quoted as `lex.Join` does, so the line can be run again as is. An
+// `i` of `len(args)` points where the next word would be. It returns only the
+// command line if `i` is out of that range.
+//
+// The words are printed as they are, so pass `ParseError.Args`, which has the
+// values of secret flags redacted, rather than the argv given to `Run`.
+func Caret(args []string, i int) string {
+	line := lex.Join(args)
+	if i < 0 || i > len(args) {
+		return line
+	}
+
+	n := 0
+	for _, v := range args[:i] {
+		n += utf8.RuneCountInString(lex.Quote(v)) + 1
+	}
+	return fmt.Sprintf("%s\n%s^", line, strings.Repeat(" ", n))
 }
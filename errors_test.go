@@ -0,0 +1,163 @@
+package xli_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/lesomnus/xli"
+	"github.com/lesomnus/xli/arg"
+	"github.com/lesomnus/xli/flg"
+	"github.com/lesomnus/xli/internal/x"
+)
+
+func TestParseError(t *testing.T) {
+	newCmd := func() *xli.Command {
+		return &xli.Command{
+			Name: "app",
+			Flags: flg.Flags{
+				&flg.Switch{Name: "verbose", Alias: 'v'},
+			},
+			Commands: xli.Commands{
+				&xli.Command{
+					Name: "serve",
+					Flags: flg.Flags{
+						&flg.Int{Name: "port"},
+						&flg.String{Name: "token", Required: true},
+						&flg.Secret{Name: "password"},
+					},
+					Args: arg.Args{
+						&arg.Int{Name: "WORKERS"},
+					},
+				},
+			},
+		}
+	}
+
+	t.Run("invalid flag value", x.F(func(x x.X) {
+		args := []string{"-v", "serve", "--port", "abc", "3"}
+		err := newCmd().Run(t.Context(), args)
+		x.True(errors.Is(err, xli.ErrInvalidFlag))
+
+		var e *xli.FlagError
+		x.True(errors.As(err, &e))
+		x.Equal([]string{"app", "serve"}, e.Path())
+		x.Equal(2, e.Index())
+		x.Equal("port", e.Info().Name)
+
+		v, ok := e.Value()
+		x.True(ok)
+		x.Equal("abc", v)
+
+		var num_err *strconv.NumError
+		x.True(errors.As(e.Cause(), &num_err))
+		x.True(errors.As(err, &num_err))
+	}))
+	t.Run("unknown flag", x.F(func(x x.X) {
+		err := newCmd().Run(t.Context(), []string{"serve", "--nope"})
+
+		var e *xli.FlagError
+		x.True(errors.As(err, &e))
+		x.True(errors.Is(err, xli.ErrUnknownFlag))
+		x.Equal(1, e.Index())
+		x.Nil(e.Info())
+		x.Equal("--nope", e.Flag().Raw())
+	}))
+	t.Run("missing flag value points past the end", x.F(func(x x.X) {
+		err := newCmd().Run(t.Context(), []string{"serve", "--port"})
+
+		var e *xli.FlagError
+		x.True(errors.As(err, &e))
+		x.True(errors.Is(err, xli.ErrNoFlagValue))
+		x.Equal(2, e.Index())
+	}))
+	t.Run("invalid argument", x.F(func(x x.X) {
+		err := newCmd().Run(t.Context(), []string{"serve", "--token=t", "many"})
+		x.True(errors.Is(err, xli.ErrInvalidArg))
+
+		var e *xli.ArgError
+		x.True(errors.As(err, &e))
+		x.Equal([]string{"app", "serve"}, e.Path())
+		x.Equal(2, e.Index())
+		x.Equal("WORKERS", e.Info().Name)
+		x.Equal("many", e.Value())
+		x.NotNil(e.Cause())
+	}))
+	t.Run("missing argument", x.F(func(x x.X) {
+		err := newCmd().Run(t.Context(), []string{"serve"})
+
+		var e *xli.ArgError
+		x.True(errors.As(err, &e))
+		x.True(errors.Is(err, xli.ErrNeedArgs))
+		x.Equal(1, e.Index())
+		x.Equal("WORKERS", e.Info().Name)
+		x.Equal("", e.Value())
+	}))
+	t.Run("unknown subcommand", x.F(func(x x.X) {
+		err := newCmd().Run(t.Context(), []string{"-v", "stop"})
+
+		var e xli.ParseError
+		x.True(errors.As(err, &e))
+		x.True(errors.Is(err, xli.ErrUnknownCmd))
+		x.Equal([]string{"app"}, e.Path())
+		x.Equal(1, e.Index())
+	}))
+	t.Run("index refers to the expanded command line", x.F(func(x x.X) {
+		resp := filepath.Join(t.TempDir(), "resp")
+		x.NoError(os.WriteFile(resp, []byte("-v serve"), 0o644))
+
+		err := newCmd().Run(t.Context(), []string{"@" + resp, "--port", "abc", "3"})
+		var e xli.ParseError
+		x.True(errors.As(err, &e))
+		x.Equal([]string{"-v", "serve", "--port", "abc", "3"}, e.Args())
+		x.Equal(2, e.Index())
+		x.Equal("-v serve --port abc 3\n         ^", xli.Caret(e.Args(), e.Index()))
+	}))
+	t.Run("secrets are redacted from the command line", x.F(func(x x.X) {
+		err := newCmd().Run(t.Context(), []string{"serve", "--password", "hunter2", "--password=hunter2", "--password=@nope", "3"})
+		var e xli.ParseError
+		x.True(errors.As(err, &e))
+		x.Equal([]string{"serve", "--password", "[redacted]", "--password=[redacted]", "--password=@nope", "3"}, e.Args())
+		x.Equal(4, e.Index())
+		x.NotContains(xli.Caret(e.Args(), e.Index()), "hunter2")
+	}))
+	t.Run("index after an alias", x.F(func(x x.X) {
+		c := newCmd()
+		c.UserAliases = &aliasStore{vs: []xli.UserAlias{{Name: "s", Args: []string{"serve", "--port"}}}}
+
+		err := c.Run(t.Context(), []string{"s", "abc", "3"})
+		var e xli.ParseError
+		x.True(errors.As(err, &e))
+		x.Equal([]string{"serve", "--port", "abc", "3"}, e.Args())
+		x.Equal(1, e.Index())
+	}))
+	t.Run("required flag", x.F(func(x x.X) {
+		err := newCmd().Run(t.Context(), []string{"serve", "3"})
+
+		var e *xli.FlagError
+		x.True(errors.As(err, &e))
+		x.True(errors.Is(err, xli.ErrFlagRequired))
+		x.Equal([]string{"app", "serve"}, e.Path())
+		x.Equal(-1, e.Index())
+		x.True(e.Info().Required)
+	}))
+}
+
+func TestCaret(t *testing.T) {
+	args := []string{"app", "--port=abc", "serve"}
+	t.Run("word", x.F(func(x x.X) {
+		x.Equal("app --port=abc serve\n    ^", xli.Caret(args, 1))
+	}))
+	t.Run("past the end", x.F(func(x x.X) {
+		x.Equal("app --port=abc serve\n                     ^", xli.Caret(args, 3))
+	}))
+	t.Run("no position", x.F(func(x x.X) {
+		x.Equal("app --port=abc serve", xli.Caret(args, -1))
+	}))
+	t.Run("quoted words", x.F(func(x x.X) {
+		args := []string{"app", "--name=John Doe", "serve"}
+		x.Equal("app '--name=John Doe' serve\n                      ^", xli.Caret(args, 2))
+	}))
+}
@@ -0,0 +1,49 @@
+package xli
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lesomnus/xli/lex"
+)
+
+// Example is a sample invocation of a command, shown in its help.
+type Example struct {
+	Brief string
+	// Cmdline is the command line following the command path,
+	// e.g. "--port=8080 web" for "app serve --port=8080 web". Words are quoted
+	// as in a POSIX shell; see `lex.Split`.
+	Cmdline string
+}
+
+// CheckExamples parses the command line of every example in the tree of `c`
+// against the tree and returns the errors of the ones that do not fit, so
+// tests can catch examples that went stale. Only the placement of commands,
+// flags, and args is checked; values are not parsed.
+func CheckExamples(c *Command) error {
+	return checkExamples(c, c, []string{})
+}
+
+// `path` holds the names of the commands from `root` (exclusive) to `c`.
+func checkExamples(root *Command, c *Command, path []string) error {
+	errs := []error{}
+	for _, v := range c.Examples {
+		words, err := lex.Split(v.Cmdline)
+		if err != nil {
+			line := strings.Join(append([]string{root.Name}, path...), " ") + " " + v.Cmdline
+			errs = append(errs, fmt.Errorf("example %q: %w", line, err))
+			continue
+		}
+
+		args := append(append([]string{}, path...), words...)
+		if _, err := parseFrameAll(root, args); err != nil {
+			line := lex.Join(append([]string{root.Name}, args...))
+			errs = append(errs, fmt.Errorf("example %q: %w", line, err))
+		}
+	}
+	for _, v := range c.Commands {
+		errs = append(errs, checkExamples(root, v, append(path, v.Name)))
+	}
+	return errors.Join(errs...)
+}
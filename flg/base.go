@@ -54,6 +54,9 @@ func (f *Base[T, P]) Info() *Info {
 		Synop:    f.Synop,
 		Usage:    f.Usage,
 		Required: f.Required,
+		Secret:   f.IsSecret(),
+
+		Repeatable: f.IsRepeatable(),
 	}
 	if f.Default != nil {
 		info.Default = f.Parser.ToString(*f.Default)
@@ -84,11 +87,18 @@ func (f *Base[T, P]) lookupDefault() (T, bool) {
 
 func (f *Base[T, P]) Handle(ctx context.Context, u string) error {
 	if m := mode.From(ctx); m == mode.Tab {
+		if f.IsSecret() {
+			// Secrets are never completed.
+			return nil
+		}
 		var z T
 		f.handle(ctx, z)
 		return nil
 	}
 
+	if p, ok := any(f.Parser).(interface{ readsFile(s string) bool }); ok && p.readsFile(u) && noFileValues(ctx) {
+		return ErrFileValue
+	}
 	v, err := f.Parser.Parse(u)
 	if err != nil {
 		return err
@@ -103,6 +113,28 @@ func (f *Base[T, P]) Count() int {
 	return f.count
 }
 
+// Reset drops the parsed value. A value with a `Zero()` method, such as
+// `SecretBytes`, is zeroed first.
+func (f *Base[T, P]) Reset() {
+	if f.Value != nil {
+		if v, ok := any(*f.Value).(interface{ Zero() }); ok {
+			v.Zero()
+		}
+	}
+	f.Value = nil
+	f.count = 0
+}
+
+// Clone returns a copy of the flag without the parsed value, for
+// `xli.Command.Invoke` to run a copy of the command tree.
+func (f *Base[T, P]) Clone() Flag {
+	v := *f
+	// The value is still the one of `f`, so it is not zeroed.
+	v.Value = nil
+	v.count = 0
+	return &v
+}
+
 func (f *Base[T, P]) setCategory(name string) {
 	f.Category = name
 }
@@ -117,6 +149,25 @@ func (f *Base[T, P]) NoValue() bool {
 	return false
 }
 
+// IsSecret reports whether the flag holds a sensitive value that must not be
+// shown. A parser opts in by implementing `IsSecret() bool`.
+func (f *Base[T, P]) IsSecret() bool {
+	if p, ok := any(f.Parser).(interface{ IsSecret() bool }); ok {
+		return p.IsSecret()
+	}
+	return false
+}
+
+// IsRepeatable reports whether the flag is meant to be given more than once,
+// e.g. a parser that accumulates the values. A parser opts in by implementing
+// `IsRepeatable() bool`.
+func (f *Base[T, P]) IsRepeatable() bool {
+	if p, ok := any(f.Parser).(interface{ IsRepeatable() bool }); ok {
+		return p.IsRepeatable()
+	}
+	return false
+}
+
 func (a *Base[T, P]) handle(ctx context.Context, v T) error {
 	if h := a.Handler; h != nil {
 		return h.Handle(ctx, v)
@@ -0,0 +1,12 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package flg
+
+import (
+	"errors"
+	"os"
+)
+
+func openFd(fd int, name string) (*os.File, error) {
+	return nil, errors.New("file descriptors are not supported on this platform")
+}
@@ -0,0 +1,18 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package flg
+
+import (
+	"os"
+	"syscall"
+)
+
+// openFd returns a duplicate of the file descriptor `fd`, so closing it leaves
+// `fd` open for the rest of the program.
+func openFd(fd int, name string) (*os.File, error) {
+	v, err := syscall.Dup(fd)
+	if err != nil {
+		return nil, err
+	}
+	return os.NewFile(uintptr(v), name), nil
+}
@@ -15,6 +15,11 @@ type Info struct {
 	Synop    string
 	Usage    fmt.Stringer
 	Required bool
+	// Secret reports that the value must not be shown, e.g. in errors.
+	Secret bool
+	// Repeatable reports that the flag is meant to be given more than once,
+	// so completion offers it again.
+	Repeatable bool
 
 	// Default is the string form of the flag's default value, for help
 	// rendering. HasDefault is false when the flag has no default.
@@ -35,6 +40,9 @@ type Flag interface {
 	Handle(ctx context.Context, v string) error
 
 	Count() int
+	// Reset clears the value parsed from the command line, so the flag can
+	// be parsed again as if it was never given.
+	Reset()
 
 	// NoValue reports whether the flag is a switch that does not consume a
 	// value (e.g. a boolean switch). Such flags default to "true" when given
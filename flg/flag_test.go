@@ -23,3 +23,13 @@ func TestFlagCount(t *testing.T) {
 	x.NoError(err)
 	x.Equal(2, v.Count())
 }
+
+type appendParser struct{ flg.StringParser }
+
+func (appendParser) IsRepeatable() bool { return true }
+
+func TestFlagRepeatable(t *testing.T) {
+	x := x.New(t)
+	x.False((&flg.String{Name: "foo"}).Info().Repeatable)
+	x.True((&flg.Base[string, appendParser]{Name: "foo"}).Info().Repeatable)
+}
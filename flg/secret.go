@@ -0,0 +1,144 @@
+package flg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Secret is a flag for sensitive values such as passwords. Its value is never
+// printed: it is redacted in help, errors, and formatted output, and it is
+// neither completed nor echoed when prompted.
+//
+// Since the command line is visible to other processes and kept in the shell
+// history, the value is better given as "@FILE" to read it from a file, as
+// "@fd:N" to read it from the file descriptor N, which is left open, or as
+// "@env:NAME" to read it from the environment variable NAME; a trailing
+// newline is dropped. A value that starts with "@" is given as "@@...".
+//
+// The value is zeroed when the flag is reset, e.g. by the next `Run`.
+type Secret = Base[SecretBytes, SecretParser]
+
+// SecretBytes is a sensitive value that formats as "[redacted]". Call `Zero`
+// once it is no longer needed.
+type SecretBytes []byte
+
+const redacted = "[redacted]"
+
+func (SecretBytes) String() string   { return redacted }
+func (SecretBytes) GoString() string { return redacted }
+
+func (SecretBytes) Format(f fmt.State, verb rune) {
+	io.WriteString(f, redacted)
+}
+
+func (SecretBytes) MarshalText() ([]byte, error) {
+	return []byte(redacted), nil
+}
+
+// Zero overwrites the value with zeros.
+func (v SecretBytes) Zero() {
+	clear(v)
+}
+
+// ErrFileValue is returned for a value that would be read from a file, a file
+// descriptor, or the environment, such as "@FILE" given to a `Secret`, in a
+// context from `WithoutFileValues`.
+var ErrFileValue = errors.New("reading the value from a file is turned off")
+
+type noFileValuesKey struct{}
+
+// WithoutFileValues returns a context in which the flags do not read their
+// values from files, file descriptors, or the environment, for running command
+// lines that come from untrusted users.
+func WithoutFileValues(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noFileValuesKey{}, true)
+}
+
+func noFileValues(ctx context.Context) bool {
+	return ctx.Value(noFileValuesKey{}) != nil
+}
+
+type SecretParser struct{}
+
+// readsFile reports whether `s` is read from a file, a file descriptor, or the
+// environment.
+func (SecretParser) readsFile(s string) bool {
+	p, ok := strings.CutPrefix(s, "@")
+	return ok && !strings.HasPrefix(p, "@")
+}
+
+func (SecretParser) Parse(s string) (SecretBytes, error) {
+	p, ok := strings.CutPrefix(s, "@")
+	if !ok {
+		return SecretBytes(s), nil
+	}
+	if strings.HasPrefix(p, "@") {
+		// "@@..." is a value starting with "@".
+		return SecretBytes(p), nil
+	}
+
+	var b []byte
+	var err error
+	if n, ok := strings.CutPrefix(p, "fd:"); ok {
+		b, err = readFd(n)
+	} else if n, ok := strings.CutPrefix(p, "env:"); ok {
+		b, err = readEnv(n)
+	} else {
+		b, err = os.ReadFile(p)
+	}
+	if err != nil {
+		clear(b)
+		return nil, err
+	}
+
+	n := len(b)
+	if bytes.HasSuffix(b, []byte("\r\n")) {
+		n -= 2
+	} else if bytes.HasSuffix(b, []byte("\n")) {
+		n -= 1
+	}
+	clear(b[n:])
+	return SecretBytes(b[:n]), nil
+}
+
+// readFd reads the file descriptor `n` through a duplicate, so the descriptor
+// itself, which the program may still use, is left open.
+func readFd(n string) ([]byte, error) {
+	fd, err := strconv.Atoi(n)
+	if err != nil || fd < 0 {
+		return nil, fmt.Errorf("invalid file descriptor %q", n)
+	}
+
+	f, err := openFd(fd, "fd:"+n)
+	if err != nil {
+		return nil, fmt.Errorf("file descriptor %s: %w", n, err)
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+func readEnv(name string) ([]byte, error) {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return nil, fmt.Errorf("environment variable %q is not set", name)
+	}
+	return []byte(v), nil
+}
+
+func (SecretParser) ToString(v SecretBytes) string {
+	return redacted
+}
+
+func (SecretParser) String() string {
+	return "secret"
+}
+
+func (SecretParser) IsSecret() bool {
+	return true
+}
@@ -0,0 +1,159 @@
+package flg_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lesomnus/xli"
+	"github.com/lesomnus/xli/flg"
+	"github.com/lesomnus/xli/internal/x"
+	"github.com/lesomnus/xli/tab"
+)
+
+func TestSecretParser(t *testing.T) {
+	p := flg.SecretParser{}
+
+	t.Run("plain value", x.F(func(x x.X) {
+		v, err := p.Parse("hunter2")
+		x.NoError(err)
+		x.Equal("hunter2", string(v))
+	}))
+	t.Run("value starting with @", x.F(func(x x.X) {
+		v, err := p.Parse("@@hunter2")
+		x.NoError(err)
+		x.Equal("@hunter2", string(v))
+	}))
+	t.Run("from a file", x.F(func(x x.X) {
+		path := filepath.Join(t.TempDir(), "password")
+		x.NoError(os.WriteFile(path, []byte("hunter2\n"), 0o600))
+
+		v, err := p.Parse("@" + path)
+		x.NoError(err)
+		x.Equal("hunter2", string(v))
+	}))
+	t.Run("from a file descriptor", x.F(func(x x.X) {
+		r, w, err := os.Pipe()
+		x.NoError(err)
+		_, err = w.WriteString("hunter2\r\n")
+		x.NoError(err)
+		x.NoError(w.Close())
+
+		v, err := p.Parse(fmt.Sprintf("@fd:%d", r.Fd()))
+		x.NoError(err)
+		x.Equal("hunter2", string(v))
+	}))
+	t.Run("file descriptor is left open", x.F(func(x x.X) {
+		r, w, err := os.Pipe()
+		x.NoError(err)
+		defer r.Close()
+		_, err = w.WriteString("hunter2\n")
+		x.NoError(err)
+		x.NoError(w.Close())
+
+		v, err := p.Parse(fmt.Sprintf("@fd:%d", r.Fd()))
+		x.NoError(err)
+		x.Equal("hunter2", string(v))
+
+		// A closed descriptor would fail instead.
+		_, err = r.Read(make([]byte, 1))
+		x.True(errors.Is(err, io.EOF))
+	}))
+	t.Run("from an environment variable", x.F(func(x x.X) {
+		t.Setenv("XLI_TEST_PASSWORD", "hunter2\n")
+		v, err := p.Parse("@env:XLI_TEST_PASSWORD")
+		x.NoError(err)
+		x.Equal("hunter2", string(v))
+
+		_, err = p.Parse("@env:XLI_TEST_NOPE")
+		x.ErrorContains(err, "not set")
+	}))
+	t.Run("invalid file descriptor", x.F(func(x x.X) {
+		_, err := p.Parse("@fd:x")
+		x.ErrorContains(err, "invalid file descriptor")
+	}))
+}
+
+func TestSecretBytes(t *testing.T) {
+	t.Run("redacted when formatted", x.F(func(x x.X) {
+		v := flg.SecretBytes("hunter2")
+		for _, f := range []string{"%v", "%s", "%q", "%x", "%#v", "%+v"} {
+			x.Equal("[redacted]", fmt.Sprintf(f, v), f)
+		}
+
+		b, err := json.Marshal(map[string]any{"password": v})
+		x.NoError(err)
+		x.Equal(`{"password":"[redacted]"}`, string(b))
+	}))
+	t.Run("zero", x.F(func(x x.X) {
+		v := flg.SecretBytes("hunter2")
+		v.Zero()
+		x.Equal(make([]byte, 7), []byte(v))
+	}))
+}
+
+func TestSecret(t *testing.T) {
+	newCmd := func() *xli.Command {
+		def := flg.SecretBytes("changeme")
+		return &xli.Command{
+			Name: "app",
+			Flags: flg.Flags{
+				&flg.Secret{Name: "password", Default: &def, Handler: flg.OnTab[flg.SecretBytes](func(ctx context.Context, t tab.Tab) error {
+					t.Value("hunter2")
+					return nil
+				})},
+			},
+		}
+	}
+
+	t.Run("info", x.F(func(x x.X) {
+		info := newCmd().Flags.Get("password").Info()
+		x.True(info.Secret)
+		x.Equal("secret", info.Type)
+		x.Equal("[redacted]", info.Default)
+	}))
+	t.Run("help", x.F(func(x x.X) {
+		b := &strings.Builder{}
+		c := newCmd()
+		c.Writer = b
+		x.NoError(c.Run(t.Context(), []string{"--help"}))
+		x.Contains(b.String(), "(default: [redacted])")
+		x.NotContains(b.String(), "changeme")
+	}))
+	t.Run("not completed", x.F(func(x x.X) {
+		b := &strings.Builder{}
+		c := newCmd()
+		c.Writer = b
+		x.NoError(c.Run(t.Context(), []string{"--password=", "$$xli_completion_zsh", "--password=", "--password="}))
+		x.Equal("", b.String())
+	}))
+	t.Run("zeroed on reset", x.F(func(x x.X) {
+		c := newCmd()
+		x.NoError(c.Run(t.Context(), []string{"--password=hunter2"}))
+		v := flg.MustGet[flg.SecretBytes](c, "password")
+		x.Equal("hunter2", string(v))
+
+		c.Reset()
+		x.Equal(make([]byte, 7), []byte(v))
+		x.Equal("changeme", string(flg.MustGet[flg.SecretBytes](c, "password")))
+	}))
+	t.Run("value is not in errors", x.F(func(x x.X) {
+		c := newCmd()
+		err := c.Run(t.Context(), []string{"--password=@" + filepath.Join(t.TempDir(), "nope")})
+		x.True(errors.Is(err, xli.ErrInvalidFlag))
+		x.Contains(err.Error(), "--password: invalid flag value")
+
+		var e *xli.FlagError
+		x.True(errors.As(err, &e))
+		x.Equal("--password", e.Flag().Raw())
+		v, ok := e.Value()
+		x.True(ok)
+		x.Equal("", v)
+	}))
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"iter"
 	"slices"
+	"strings"
 	"unicode/utf8"
 
 	"github.com/lesomnus/xli/arg"
@@ -27,6 +28,13 @@ type frame struct {
 	rest   []string // args for next command
 	remain []string // remain args after end of command
 
+	// argv is the whole command line being parsed, and the indexes are of
+	// the words above in it, for error reporting.
+	argv      []string
+	flags_at  []int
+	args_at   []int
+	remain_at int
+
 	is_help bool
 }
 
@@ -79,14 +87,15 @@ func (f *frame) Last() *frame {
 // Collects flags, args, and subcommands to be executed are without paring.
 // Collected information are stored in the `frame` for each command.
 // Root frame, `f_root`, holds `c`.
-func parseFrameAll(cmd *Command, args_rest []string) (*frame, error) {
+func parseFrameAll(cmd *Command, args []string) (*frame, error) {
 	root := &frame{
 		c_next: cmd,
-		rest:   args_rest,
+		rest:   args,
+		argv:   args,
 	}
 	for f := root; f.c_next != nil; f = f.next {
-		f_next, err := parseFrame(f.c_next, f.rest)
-		f_next.prev = f
+		// `f.rest` is always a suffix of `args`.
+		f_next, err := parseFrame(f, f.c_next, f.rest, len(args)-len(f.rest))
 		f.next = f_next
 		if err != nil {
 			return root.next, err
@@ -102,7 +111,8 @@ func parseFrameAll(cmd *Command, args_rest []string) (*frame, error) {
 // Parses flags, args, and subcommand for given cmd.
 // It stops parsing if subcommand is found.
 // It does not parse the arguments into Flag or Arg but looks only its placement.
-func parseFrame(cmd *Command, args_rest []string) (*frame, error) {
+// `at` is the index of `args_rest[0]` in the argv given to `Run`.
+func parseFrame(prev *frame, cmd *Command, args_rest []string, at int) (*frame, error) {
 	is_opt := slices.ContainsFunc(cmd.Args, func(a arg.Arg) bool {
 		return a.IsOptional()
 	})
@@ -111,24 +121,38 @@ func parseFrame(cmd *Command, args_rest []string) (*frame, error) {
 	}
 
 	f := &frame{
+		prev:   prev,
+		argv:   prev.argv,
 		c_curr: cmd,
 		flags:  []lex.Flag{},
 		args:   []string{},
+
+		flags_at: []int{},
+		args_at:  []int{},
+	}
+	if cmd.exe != "" {
+		// The rest of the command line belongs to the plugin.
+		for i, v := range args_rest {
+			f.args = append(f.args, v)
+			f.args_at = append(f.args_at, at+i)
+		}
+		return f, nil
 	}
 	for i := 0; i < len(args_rest); i++ {
 		t := lex.Lex(args_rest[i])
 		switch v := t.(type) {
 		case *lex.Err:
-			return f, v
+			return f, f.flagError(at+i, lex.Flag(v.Raw()), nil, v)
 
 		case lex.EndOfCommand:
 			f.rest = []string{}
 			f.remain = args_rest[i:]
+			f.remain_at = at + i
 			return f, nil
 
 		case lex.Flag:
 			if len(f.args) > 0 {
-				return f, &FlagError{v, ErrFlagAfterArg}
+				return f, f.flagError(at+i, v, nil, ErrFlagAfterArg)
 			}
 			if n := v.Name(); n == "help" || n == "h" {
 				f.is_help = true
@@ -144,8 +168,11 @@ func parseFrame(cmd *Command, args_rest []string) (*frame, error) {
 			}
 
 			if w == nil {
-				return f, &FlagError{v, ErrUnknownFlag}
-			} else if w.NoValue() {
+				return f, f.flagError(at+i, v, nil, ErrUnknownFlag)
+			}
+
+			j := at + i
+			if w.NoValue() {
 				// Flag is a switch and does not consume a value.
 				if _, ok := v.Arg(); !ok {
 					v = v.WithArg("true")
@@ -155,18 +182,18 @@ func parseFrame(cmd *Command, args_rest []string) (*frame, error) {
 				i++
 				if i == len(args_rest) {
 					// There are no more args.
-					return f, &FlagError{v, ErrNoFlagValue}
+					return f, f.flagError(at+i, v, w.Info(), ErrNoFlagValue)
 				}
 
-				switch w := lex.Lex(args_rest[i]).(type) {
+				switch u := lex.Lex(args_rest[i]).(type) {
 				case *lex.Err:
-					return f, fmt.Errorf("%s: %w", v, w)
+					return f, f.flagError(at+i, v, w.Info(), u)
 				case lex.EndOfCommand:
-					return f, &FlagError{v, ErrNoFlagValue}
+					return f, f.flagError(at+i, v, w.Info(), ErrNoFlagValue)
 				case lex.Flag:
-					return f, &FlagError{v, ErrNoFlagValue}
+					return f, f.flagError(at+i, v, w.Info(), ErrNoFlagValue)
 				case lex.Arg:
-					v = v.WithArg(w)
+					v = v.WithArg(u)
 				default:
 					// Unreachable: lex.Lex only returns *Err, EndOfCommand, Flag, or Arg.
 					panic("xli: unreachable: lex.Lex returned an unexpected token type")
@@ -174,19 +201,24 @@ func parseFrame(cmd *Command, args_rest []string) (*frame, error) {
 			}
 
 			f.flags = append(f.flags, v)
+			f.flags_at = append(f.flags_at, j)
 
 		case lex.Arg:
 			if is_opt || len(f.args) < len(cmd.Args) {
 				f.args = append(f.args, v.Raw())
+				f.args_at = append(f.args_at, at+i)
 				continue
 			}
-			if len(cmd.Commands) == 0 {
-				return f, &ArgError{v, ErrTooManyArgs}
+			if len(cmd.Commands) == 0 && cmd.Plugins == nil {
+				return f, f.argError(at+i, v, nil, ErrTooManyArgs)
 			}
 
 			f.c_next = cmd.Commands.Get(v.Raw())
 			if f.c_next == nil {
-				return f, &ArgError{v, ErrUnknownCmd}
+				f.c_next = cmd.plugin(f.path(), v.Raw())
+			}
+			if f.c_next == nil {
+				return f, f.argError(at+i, v, nil, ErrUnknownCmd)
 			}
 
 			// Subcommand is found so stop parsing.
@@ -202,8 +234,7 @@ func parseFrame(cmd *Command, args_rest []string) (*frame, error) {
 	if i := len(f.args); i < len(cmd.Args) {
 		a := cmd.Args[i]
 		if !a.IsOptional() {
-			name := fmt.Sprintf("%q", a.Info().Name)
-			return f, &ArgError{lex.Arg(name), ErrNeedArgs}
+			return f, f.argError(at+len(args_rest), "", a.Info(), ErrNeedArgs)
 		}
 	}
 
@@ -214,29 +245,11 @@ func parseFrame(cmd *Command, args_rest []string) (*frame, error) {
 // Prepares the command associated with the frame.
 // Flag and Arg parser will be executed and runs next frame if exists.
 func (f *frame) prepare(ctx context.Context) error {
-	c := f.c_curr
-	for _, v := range f.flags {
-		var h flg.Flag
-		if v.IsShort() {
-			r, _ := utf8.DecodeRuneInString(v.Name())
-			h = c.Flags.GetByAlias(r)
-		} else {
-			h = c.Flags.Get(v.Name())
-		}
-
-		if h == nil {
-			return fmt.Errorf("%s: %w", v.Name(), ErrUnknownFlag)
-		}
-
-		a, ok := v.Arg()
-		if !ok {
-			a = lex.Arg("true")
-		}
-		if err := h.Handle(ctx, a.Raw()); err != nil {
-			return fmt.Errorf("invalid flag: %s: %w", v, err)
-		}
+	if err := f.prepareFlags(ctx); err != nil {
+		return err
 	}
 
+	c := f.c_curr
 	i := 0
 	for _, h := range c.Args {
 		if i == len(f.args) {
@@ -247,7 +260,7 @@ func (f *frame) prepare(ctx context.Context) error {
 				break
 			}
 
-			return fmt.Errorf("%w: %q", ErrNeedArgs, h.Info().Name)
+			return f.argError(f.argsEnd(), "", h.Info(), ErrNeedArgs)
 		}
 
 		// Parser can consume multiple arguments.
@@ -256,7 +269,9 @@ func (f *frame) prepare(ctx context.Context) error {
 			panic(fmt.Sprintf(`argument parser reported that it parsed more arguments than were given: "%s" parse %v`, h.Info().Name, f.args[i:]))
 		}
 		if err != nil {
-			return fmt.Errorf("invalid argument: %q: %w", f.args[i], err)
+			e := f.argError(f.args_at[i], lex.Arg(f.args[i]), h.Info(), ErrInvalidArg)
+			e.cause = err
+			return e
 		}
 		if h_ := h.Info().Handle; h_ != nil {
 			h_(ctx)
@@ -269,13 +284,15 @@ func (f *frame) prepare(ctx context.Context) error {
 			if len(f.remain) > 0 {
 				_, err := h.Parse(f.remain)
 				if err != nil {
-					return fmt.Errorf("invalid argument: %q: %w", f.remain, err)
+					e := f.argError(f.remain_at, lex.Arg(f.remain[0]), h.Info(), ErrInvalidArg)
+					e.cause = err
+					return e
 				}
 				if h_ := h.Info().Handle; h_ != nil {
 					h_(ctx)
 				}
 			} else if !h.IsOptional() {
-				return fmt.Errorf("%w: %q", ErrNeedArgs, h.Info().Name)
+				return f.argError(f.argsEnd(), "", h.Info(), ErrNeedArgs)
 			}
 		}
 	}
@@ -283,40 +300,205 @@ func (f *frame) prepare(ctx context.Context) error {
 	return nil
 }
 
+// prepareFlags parses the flags of the frame into the command's flags.
+func (f *frame) prepareFlags(ctx context.Context) error {
+	c := f.c_curr
+	for j, v := range f.flags {
+		var h flg.Flag
+		if v.IsShort() {
+			r, _ := utf8.DecodeRuneInString(v.Name())
+			h = c.Flags.GetByAlias(r)
+		} else {
+			h = c.Flags.Get(v.Name())
+		}
+
+		if h == nil {
+			return f.flagError(f.flags_at[j], v, nil, ErrUnknownFlag)
+		}
+
+		a, ok := v.Arg()
+		if !ok {
+			a = lex.Arg("true")
+		}
+		if err := h.Handle(ctx, a.Raw()); err != nil {
+			e := f.flagError(f.flags_at[j], v, h.Info(), ErrInvalidFlag)
+			e.cause = err
+			return e
+		}
+	}
+
+	return nil
+}
+
+// path returns the names of the commands from the root to this frame.
+func (f *frame) path() []string {
+	vs := []string{}
+	for ; f != nil; f = f.prev {
+		if f.c_curr != nil {
+			vs = append(vs, f.c_curr.Name)
+		}
+	}
+	slices.Reverse(vs)
+	return vs
+}
+
+// argsEnd returns the index in argv just past the last argument of the frame,
+// or -1 if no argument is given.
+func (f *frame) argsEnd() int {
+	if n := len(f.args_at); n > 0 {
+		return f.args_at[n-1] + 1
+	}
+	return -1
+}
+
+func (f *frame) flagError(i int, v lex.Flag, info *flg.Info, err error) *FlagError {
+	return &FlagError{path: f.path(), args: f.safeArgv(), index: i, flag: v, info: info, err: err}
+}
+
+func (f *frame) argError(i int, v lex.Arg, info *arg.Info, err error) *ArgError {
+	return &ArgError{path: f.path(), args: f.safeArgv(), index: i, arg: v, info: info, err: err}
+}
+
+// safeArgv returns the command line with the values of secret flags redacted,
+// for errors that may be printed.
+func (f *frame) safeArgv() []string {
+	r := f
+	for r.prev != nil && r.prev.c_curr != nil {
+		r = r.prev
+	}
+	vs, _ := redactSecrets(r.c_curr, f.argv)
+	return vs
+}
+
 // Executes the command associated with the frame.
 // Unlike prepare, it executes next frame also.
 func (f *frame) execute(ctx context.Context) error {
 	c := f.c_curr
-	if c.Handler == nil {
-		c.Handler = noop
+	h := c.Handler
+	if h == nil {
+		h = noop
 	}
 
 	ctx = frm.Into(ctx, f)
 
 	if next := f.next; next != nil {
 		next.c_curr.parent = c
-		return c.Handler.Handle(ctx, c, func(ctx context.Context) error {
-			c_next := next.c_curr
-			if c_next.ReadCloser == nil {
-				c_next.ReadCloser = c.ReadCloser
-			}
-			if c_next.Writer == nil {
-				c_next.Writer = c.Writer
-			}
-			if c_next.ErrWriter == nil {
-				c_next.ErrWriter = c.ErrWriter
-			}
-
+		return h.Handle(ctx, c, func(ctx context.Context) error {
+			next.c_curr.inheritIO(c.ReadCloser, c.Writer, c.ErrWriter)
 			return next.execute(ctx)
 		})
 	}
 
 	m := mode.From(ctx)
 	ctx = mode.Into(ctx, m.NoPass())
-	return c.Handler.Handle(ctx, c, func(ctx context.Context) error {
+	return h.Handle(ctx, c, func(ctx context.Context) error {
 		if f.is_help {
 			return c.PrintHelp(c)
 		}
 		return nil
 	})
 }
+
+// redactSecrets returns `args` with the values given to the secret flags of
+// `c` and its subcommands replaced by "[redacted]", and whether any was. A
+// value that refers to a file or a file descriptor, such as "@FILE", is kept
+// since it is not the secret itself.
+func redactSecrets(c *Command, args []string) ([]string, bool) {
+	is_secret := func(h flg.Flag, v string) bool {
+		if h == nil || !h.Info().Secret {
+			return false
+		}
+		p, ok := strings.CutPrefix(v, "@")
+		return !ok || p == "" || strings.HasPrefix(p, "@")
+	}
+
+	vs := slices.Clone(args)
+	redacted := false
+	s := newFlagScan(c)
+	for i, v := range vs {
+		if v == "--" {
+			break
+		}
+		h, is_value := s.next(v)
+		if is_value {
+			if is_secret(h, v) {
+				vs[i] = "[redacted]"
+				redacted = true
+			}
+			continue
+		}
+		t, ok := lex.Lex(v).(lex.Flag)
+		if !ok {
+			continue
+		}
+		if a, ok := t.Arg(); ok && is_secret(h, a.Raw()) {
+			vs[i] = t.WithArg(lex.Arg("[redacted]")).Raw()
+			redacted = true
+		}
+	}
+	return vs, redacted
+}
+
+// flagScan follows a command line word by word, as far as its flags and
+// subcommands go, to tell the words given as the values of flags and the words
+// given to a plugin without parsing it.
+type flagScan struct {
+	cmd *Command
+	// path holds the names of the commands from the root to `cmd`.
+	path []string
+	// n is the number of arguments given to `cmd`.
+	n int
+	// value is the flag the next word is the value of.
+	value flg.Flag
+	// plugin is set once a plugin is found; the rest of the words are its.
+	plugin bool
+}
+
+func newFlagScan(c *Command) *flagScan {
+	return &flagScan{cmd: c, path: []string{c.Name}}
+}
+
+// next follows the word `v`. It returns the flag `v` is the value of and true,
+// or the flag `v` itself is, if it is a known one, and false. For a word given
+// to a plugin, it returns nil and true, as the word is the plugin's to read.
+func (s *flagScan) next(v string) (flg.Flag, bool) {
+	if s == nil {
+		return nil, false
+	}
+	if s.plugin {
+		return nil, true
+	}
+	if h := s.value; h != nil {
+		s.value = nil
+		return h, true
+	}
+
+	switch t := lex.Lex(v).(type) {
+	case lex.Flag:
+		var h flg.Flag
+		if t.IsShort() {
+			r, _ := utf8.DecodeRuneInString(t.Name())
+			h = s.cmd.Flags.GetByAlias(r)
+		} else {
+			h = s.cmd.Flags.Get(t.Name())
+		}
+		if _, ok := t.Arg(); !ok && h != nil && !h.NoValue() {
+			s.value = h
+		}
+		return h, false
+	case lex.Arg:
+		is_opt := slices.ContainsFunc(s.cmd.Args, func(a arg.Arg) bool {
+			return a.IsOptional()
+		})
+		if is_opt || s.n < len(s.cmd.Args) {
+			s.n++
+		} else if u := s.cmd.Commands.Get(t.Raw()); u != nil {
+			s.cmd = u
+			s.path = append(s.path, u.Name)
+			s.n = 0
+		} else if s.cmd.plugin(s.path, t.Raw()) != nil {
+			s.plugin = true
+		}
+	}
+	return nil, false
+}
@@ -25,6 +25,7 @@ func (a *twoArg) Parse(rest []string) (int, error) {
 }
 func (a *twoArg) IsOptional() bool { return false }
 func (a *twoArg) IsMany() bool     { return false }
+func (a *twoArg) Reset()           {}
 
 func TestPrepareMissingArg(t *testing.T) {
 	t.Run("missing required arg returns error instead of panicking", x.F(func(x x.X) {
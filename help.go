@@ -0,0 +1,417 @@
+package xli
+
+import (
+	"context"
+	_ "embed"
+	"io"
+	"slices"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+
+	"github.com/lesomnus/xli/arg"
+	"github.com/lesomnus/xli/flg"
+	"github.com/lesomnus/xli/frm"
+	"github.com/lesomnus/xli/internal/term"
+	"github.com/lesomnus/xli/lex"
+	"github.com/lesomnus/xli/tab"
+)
+
+//go:embed help.go.tpl
+var DefaultHelpTemplate string
+
+// defaultHelpTemplate is parsed once at startup; the embedded template is a
+// compile-time constant, so a parse failure is a programmer error.
+var defaultHelpTemplate = template.Must(ParseHelpTemplate(DefaultHelpTemplate))
+
+// HelpFuncs are the functions available to help templates:
+//
+//	wrap WIDTH TEXT    wraps TEXT at word boundaries into lines of at most WIDTH columns
+//	indent N TEXT      prefixes every non-empty line of TEXT with N spaces
+//	hang N TEXT        like indent but leaves the first line as is
+//	pad N TEXT         pads TEXT with spaces on the right to N columns
+//	upper TEXT         converts TEXT to upper case
+//	join SEP LIST      joins the strings in LIST with SEP
+//	add A B, sub A B   integer arithmetic for computing widths
+//	max A B            the greater of A and B, e.g. to keep a width from
+//	                   going below a minimum
+//
+// The text argument comes last so the functions compose in pipelines, e.g.
+// `{{ .Synop | wrap 72 | indent 4 }}`.
+var HelpFuncs = template.FuncMap{
+	"wrap":   wrap,
+	"indent": indent,
+	"hang":   hang,
+	"pad":    pad,
+	"upper":  strings.ToUpper,
+	"join":   func(sep string, vs []string) string { return strings.Join(vs, sep) },
+	"add":    func(a, b int) int { return a + b },
+	"sub":    func(a, b int) int { return a - b },
+	"max":    func(a, b int) int { return max(a, b) },
+}
+
+// ParseHelpTemplate parses a help template with `HelpFuncs` available.
+func ParseHelpTemplate(text string) (*template.Template, error) {
+	return template.New("help").Funcs(HelpFuncs).Parse(text)
+}
+
+// HelpData is the data a help template is executed with.
+type HelpData struct {
+	// Command is the command whose help is printed.
+	Command *Command
+
+	// Path holds the names of the commands from the root to `Command`.
+	Path  []string
+	Brief string
+	Synop string
+
+	// Usage is the usage line such as "app serve <PORT> [options] [command]".
+	Usage string
+
+	// Args holds the arguments of every command in the path, in order, as they
+	// all appear in the usage line.
+	Args []*arg.Info
+
+	Flags    []HelpFlagGroup
+	Commands []HelpCommandGroup
+	Examples []HelpExample
+
+	// Width is the number of columns the message should fit in.
+	Width int
+	// LabelWidth is the width of the widest flag or subcommand label, so their
+	// descriptions can be aligned in a column.
+	LabelWidth int
+
+	// Style styles the elements of the message, e.g. `{{ .Style.Heading "Usage:" }}`.
+	// The width functions (`wrap`, `pad`) do not count the styling.
+	Style Style
+}
+
+// HelpFlagGroup is a set of flags in the same category. The category of the
+// first group is empty if there are flags without a category.
+type HelpFlagGroup struct {
+	Category string
+	Flags    []*flg.Info
+}
+
+// HelpCommandGroup is a set of subcommands in the same category.
+type HelpCommandGroup struct {
+	Category string
+	Commands []*Command
+}
+
+// HelpExample is an `Example` with its command line prefixed by the command path.
+type HelpExample struct {
+	Brief   string
+	Cmdline string
+}
+
+// NewHelpData collects the data for the help message of `c` from the tree
+// alone, so it is the same on every machine; `PrintHelp` adds the user
+// aliases and the plugins to it.
+func NewHelpData(c *Command) *HelpData {
+	d := &HelpData{
+		Command: c,
+		Path:    []string{},
+		Brief:   c.Brief,
+		Synop:   c.Synop,
+		Args:    []*arg.Info{},
+
+		Flags:    []HelpFlagGroup{},
+		Commands: []HelpCommandGroup{},
+		Examples: []HelpExample{},
+
+		Width: term.DefaultWidth,
+		Style: c.style(io.Discard),
+	}
+
+	usage := []string{}
+	for _, v := range c.Tree() {
+		d.Path = append(d.Path, v.Name)
+		usage = append(usage, v.Name)
+		for _, a := range v.Args {
+			info := a.Info()
+			d.Args = append(d.Args, info)
+			usage = append(usage, info.Usage.String())
+		}
+	}
+	if len(c.Flags) > 0 {
+		usage = append(usage, "[options]")
+	}
+	if len(c.Commands.Visible()) > 0 || c.Plugins != nil {
+		usage = append(usage, "[command]")
+	}
+	d.Usage = strings.Join(usage, " ")
+
+	for _, g := range c.Flags.ByCategory() {
+		vs := make([]*flg.Info, len(g))
+		for i, f := range g {
+			vs[i] = f.Info()
+			d.LabelWidth = max(d.LabelWidth, utf8.RuneCountInString(vs[i].String()))
+		}
+		d.Flags = append(d.Flags, HelpFlagGroup{Category: vs[0].Category, Flags: vs})
+	}
+	for _, g := range c.Commands.Visible().ByCategory() {
+		d.addCommands(g)
+	}
+
+	prog := strings.Join(d.Path, " ")
+	for _, v := range c.Examples {
+		line := prog
+		if v.Cmdline != "" {
+			line += " " + v.Cmdline
+		}
+		d.Examples = append(d.Examples, HelpExample{Brief: v.Brief, Cmdline: line})
+	}
+
+	return d
+}
+
+// addCommands adds `g`, subcommands in the same category, to the help.
+func (d *HelpData) addCommands(g Commands) {
+	if len(g) == 0 {
+		return
+	}
+	d.Commands = append(d.Commands, HelpCommandGroup{Category: g[0].Category, Commands: g})
+	for _, v := range g {
+		d.LabelWidth = max(d.LabelWidth, utf8.RuneCountInString(v.String()))
+	}
+}
+
+// helpTemplate returns the nearest help template up the tree.
+func (c *Command) helpTemplate() *template.Template {
+	for p := c; p != nil; p = p.parent {
+		if p.HelpTemplate != nil {
+			return p.HelpTemplate
+		}
+	}
+	return defaultHelpTemplate
+}
+
+// PrintHelp writes the help message of `c` to `w`, fitted to the width of the
+// terminal `w` writes to.
+func (c *Command) PrintHelp(w io.Writer) error {
+	d := NewHelpData(c)
+	d.addCommands(c.userAliasCommands())
+	d.addCommands(c.pluginCommands())
+
+	// The command itself is passed as `w` when printing for "--help".
+	o := w
+	if c_, ok := w.(*Command); ok {
+		o = c_.Writer
+	}
+	d.Width = term.Width(o)
+	d.Style = c.style(o)
+
+	return c.RenderHelp(w, d)
+}
+
+// RenderHelp writes the help message made from `d` to `w` with the nearest
+// help template up the tree. With the data of `NewHelpData`, it leaves out
+// what `PrintHelp` adds from the user's environment, such as user aliases and
+// plugins.
+func (c *Command) RenderHelp(w io.Writer, d *HelpData) error {
+	return c.helpTemplate().Execute(w, d)
+}
+
+// PrintTree writes `c` and its descendants that are not hidden as a tree
+// indented by depth, with their briefs aligned in a column.
+func (c *Command) PrintTree(w io.Writer) error {
+	type row struct {
+		label string
+		brief string
+	}
+
+	o := w
+	if c_, ok := w.(*Command); ok {
+		o = c_.Writer
+	}
+	s := c.style(o)
+
+	rows := []row{{label: s.Command(c.String()), brief: c.Brief}}
+	var walk func(c *Command, depth int)
+	walk = func(c *Command, depth int) {
+		for _, g := range c.Commands.Visible().ByCategory() {
+			d := depth
+			if cat := g[0].Category; cat != "" {
+				rows = append(rows, row{label: strings.Repeat("  ", d) + s.Heading(cat+":")})
+				d++
+			}
+			for _, v := range g {
+				v.parent = c
+				rows = append(rows, row{label: strings.Repeat("  ", d) + s.Command(v.String()), brief: v.Brief})
+				walk(v, d+1)
+			}
+		}
+	}
+	walk(c, 1)
+
+	label_w := 0
+	for _, r := range rows {
+		label_w = max(label_w, textWidth(r.label))
+	}
+	col := label_w + 2
+	desc_w := max(term.Width(o)-col, 20)
+
+	b := &strings.Builder{}
+	for _, r := range rows {
+		if r.brief == "" {
+			b.WriteString(r.label)
+		} else {
+			b.WriteString(pad(label_w, r.label))
+			b.WriteString("  ")
+			b.WriteString(hang(col, wrap(desc_w, r.brief)))
+		}
+		b.WriteByte('\n')
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// PrintHelpAll writes the help messages of `c` and its descendants that are
+// not hidden, one after another.
+func (c *Command) PrintHelpAll(w io.Writer) error {
+	i := 0
+	return Walk(c, func(path []*Command) error {
+		if slices.ContainsFunc(path, func(v *Command) bool { return v.Hidden }) {
+			return nil
+		}
+		if i > 0 {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		i++
+		return path[len(path)-1].PrintHelp(w)
+	})
+}
+
+// NewCmdHelp returns a "help" command that prints the help message of the
+// command it is mounted in, or of the descendant at the path given as its
+// arguments, e.g. "app help server start". With "--all", it lists every
+// command below as a tree instead, or prints all of their help messages with
+// "--long" as well.
+func NewCmdHelp() *Command {
+	return &Command{
+		Name:  "help",
+		Brief: "Show help for a command",
+		Flags: flg.Flags{
+			&flg.Switch{Name: "all", Alias: 'a', Brief: "list every command below"},
+			&flg.Switch{Name: "long", Alias: 'l', Brief: "with --all, print the full help of every command"},
+		},
+		Args: arg.Args{
+			&arg.RestStrings{
+				Name:  "COMMAND",
+				Brief: "path to the command",
+				Handler: arg.OnTab[[]string](func(ctx context.Context, t tab.Tab) {
+					f, ok := frm.From(ctx).(*frame)
+					if !ok {
+						return
+					}
+					if c, err := f.helpTarget(); err == nil {
+						completeCommands(t, c)
+					}
+				}),
+			},
+		},
+		Handler: OnRun(func(ctx context.Context, cmd *Command, next Next) error {
+			f, ok := frm.From(ctx).(*frame)
+			if !ok {
+				return next(ctx)
+			}
+
+			c, err := f.helpTarget()
+			if err != nil {
+				return err
+			}
+
+			all, _ := flg.Get[bool](cmd, "all")
+			long, _ := flg.Get[bool](cmd, "long")
+			switch {
+			case all && long:
+				err = c.PrintHelpAll(cmd)
+			case all:
+				err = c.PrintTree(cmd)
+			default:
+				err = c.PrintHelp(cmd)
+			}
+			if err != nil {
+				return err
+			}
+			return next(ctx)
+		}),
+	}
+}
+
+// helpTarget resolves the arguments given to the help command of the frame
+// into a command, starting from the command the help command is mounted in.
+func (f *frame) helpTarget() (*Command, error) {
+	c := f.c_curr.parent
+	if c == nil {
+		c = f.c_curr
+	}
+	for i, name := range f.args {
+		v := c.Commands.Get(name)
+		if v == nil {
+			return nil, f.argError(f.args_at[i], lex.Arg(name), nil, ErrUnknownCmd)
+		}
+		v.parent = c
+		c = v
+	}
+	return c, nil
+}
+
+// wrap breaks lines of `s` at spaces so that they fit in `width` columns.
+// A word longer than `width` is left on its own line as is.
+func wrap(width int, s string) string {
+	b := &strings.Builder{}
+	for i, line := range strings.Split(s, "\n") {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+
+		n := 0
+		for j, word := range strings.Fields(line) {
+			l := textWidth(word)
+			if j > 0 {
+				if n+1+l > width {
+					b.WriteByte('\n')
+					n = 0
+				} else {
+					b.WriteByte(' ')
+					n++
+				}
+			}
+			b.WriteString(word)
+			n += l
+		}
+	}
+	return b.String()
+}
+
+func indent(n int, s string) string {
+	pad := strings.Repeat(" ", n)
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = pad + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func hang(n int, s string) string {
+	first, rest, ok := strings.Cut(s, "\n")
+	if !ok {
+		return s
+	}
+	return first + "\n" + indent(n, rest)
+}
+
+func pad(n int, s string) string {
+	if l := textWidth(s); l < n {
+		return s + strings.Repeat(" ", n-l)
+	}
+	return s
+}
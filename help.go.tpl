@@ -1,72 +1,60 @@
-{{ with $.Tree -}}
-	{{ $root := index . 0 -}}
-	{{ $rest := slice . 1 -}}
-
-Name:
-{{ print "    " $root.Name -}}
-	{{ range $rest -}}
-		{{ print "." .Name -}}
-	{{ end -}}
-	{{ if $.Brief -}}
-		{{ print " - " $.Brief -}}
-	{{ end }}
-
-Usage:
-{{ print "    " -}}
-	{{ range . -}}
-		{{ print .Name " " -}}
-		{{ range .Args -}}
-			{{ print .Info.Usage.String " " -}}
-		{{ end -}}
-	{{ end -}}
-	{{ if len $.Flags    | ne 0 }}[options] {{ end -}}
-	{{ if len $.Commands | ne 0 }}[command]{{ end -}}
-	{{ range . -}}
-		{{ if len .Args | ne 0 -}}
-			{{ printf "\n" -}}
-		{{ end -}}
-
-		{{ range .Args -}}
-			{{ if len .Brief | ne 0 -}}
-				{{ printf "\n  %s:\n    %s" .Name .Brief -}}
-				{{ if .Info.HasDefault }}{{ printf " (default: %s)" .Info.Default -}}{{ end -}}
-				{{ print "\n" -}}
-			{{ end -}}
-		{{ end -}}
+{{- /* Executed with xli.HelpData. */ -}}
+{{- $col := add 6 .LabelWidth -}}
+{{- /* Narrow as the terminal may be, descriptions keep a few words a line. */ -}}
+{{- $desc_w := max 20 (sub .Width $col) -}}
+{{ .Style.Heading "Name:" }}
+{{ print "    " (join "." .Path) -}}
+{{ if .Brief }}{{ print " - " .Brief }}{{ end }}
+
+{{ .Style.Heading "Usage:" }}
+{{ print "    " .Usage }}
+{{- range .Args -}}
+	{{ if .Brief -}}
+		{{ $d := .Brief -}}
+		{{ if .HasDefault }}{{ $d = print $d " " ($.Style.Default (printf "(default: %s)" .Default)) }}{{ end -}}
+		{{ printf "\n\n  %s:\n%s" .Name ($d | wrap (sub $.Width 4) | indent 4) -}}
 	{{ end -}}
 {{ end -}}
-{{ if $.Synop }}
 
-Description:
-{{ printf "    %s" $.Synop -}}
+{{ if .Synop }}
+
+{{ .Style.Heading "Description:" }}
+{{ .Synop | wrap (sub .Width 4) | indent 4 -}}
 {{ end -}}
 
-{{ if len $.Commands | ne 0 }}
+{{ if .Commands }}
 
-Commands:{{ range $.Commands.ByCategory -}}
-		{{ $category := (index . 0).Category -}}
-		{{ if len $category | ne 0 -}}
-			{{ printf "\n  %s:" $category -}}
-		{{ end -}}
-		{{ range . -}}
-			{{ printf "\n    %-20s %s" .String .Brief -}}
+{{ .Style.Heading "Commands:" }}
+	{{- range .Commands -}}
+		{{ if .Category }}{{ printf "\n  %s:" .Category }}{{ end -}}
+		{{ range .Commands -}}
+			{{ print "\n    " -}}
+			{{ if .Brief }}{{ pad $.LabelWidth ($.Style.Command .String) }}  {{ .Brief | wrap $desc_w | hang $col }}{{ else }}{{ $.Style.Command .String }}{{ end -}}
 		{{ end -}}
 	{{ end -}}
 {{ end -}}
 
-{{ if len $.Flags | ne 0 }}
-
-Options:{{ range $.Flags.ByCategory -}}
-		{{ $category := (index . 0).Info.Category -}}
-		{{ if len $category | ne 0 -}}
-			{{ printf "\n  %s:" $category -}}
+{{ if .Flags }}
+
+{{ .Style.Heading "Options:" }}
+	{{- range .Flags -}}
+		{{ if .Category }}{{ printf "\n  %s:" .Category }}{{ end -}}
+		{{ range .Flags -}}
+			{{ $d := .Brief -}}
+			{{ if .Required }}{{ $d = print $d " " ($.Style.Required "(required)") }}{{ end -}}
+			{{ if .HasDefault }}{{ $d = print $d " " ($.Style.Default (printf "(default: %s)" .Default)) }}{{ end -}}
+			{{ print "\n    " -}}
+			{{ if $d }}{{ pad $.LabelWidth ($.Style.Flag .String) }}  {{ $d | wrap $desc_w | hang $col }}{{ else }}{{ $.Style.Flag .String }}{{ end -}}
 		{{ end -}}
-		{{ range . -}}{{ with .Info -}}
-			{{ printf "\n    %-20s %s" .String .Brief -}}
-			{{ if .Required }}{{ print " (required)" -}}{{ end -}}
-			{{ if .HasDefault }}{{ printf " (default: %s)" .Default -}}{{ end -}}
-		{{ end -}}{{ end -}}
 	{{ end -}}
 {{ end -}}
 
-{{ print "\n" -}}
+{{ if .Examples }}
+
+{{ .Style.Heading "Examples:" }}
+	{{- range $i, $v := .Examples -}}
+		{{ if $i }}{{ print "\n" }}{{ end -}}
+		{{ if .Brief }}{{ printf "\n%s" (print "# " .Brief | wrap (sub $.Width 4) | indent 4) }}{{ end -}}
+		{{ printf "\n    %s" .Cmdline -}}
+	{{ end -}}
+{{ end }}
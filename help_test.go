@@ -1,8 +1,10 @@
 package xli_test
 
 import (
+	"errors"
 	"strings"
 	"testing"
+	"text/template"
 
 	"github.com/lesomnus/xli"
 	"github.com/lesomnus/xli/arg"
@@ -94,3 +96,348 @@ func TestPrintHelp(t *testing.T) {
 		x.Contains(b.String(), "echo [STRING...]")
 	}))
 }
+
+func TestHelpTemplate(t *testing.T) {
+	t.Run("custom template is used", x.F(func(x x.X) {
+		c := &xli.Command{
+			Name:         "app",
+			Brief:        "does things",
+			HelpTemplate: template.Must(xli.ParseHelpTemplate(`{{ .Brief | upper }}`)),
+		}
+
+		b := &strings.Builder{}
+		err := c.PrintHelp(b)
+		x.NoError(err)
+		x.Equal("DOES THINGS", b.String())
+	}))
+	t.Run("template is inherited by subcommands", x.F(func(x x.X) {
+		c := &xli.Command{
+			Name:         "app",
+			HelpTemplate: template.Must(xli.ParseHelpTemplate(`{{ join " " .Path }}: {{ .Usage }}`)),
+			Commands: xli.Commands{
+				&xli.Command{
+					Name: "serve",
+					Args: arg.Args{&arg.String{Name: "ADDR"}},
+				},
+			},
+		}
+
+		b := &strings.Builder{}
+		c.Writer = b
+		err := c.Run(t.Context(), []string{"serve", "--help"})
+		x.NoError(err)
+		x.Equal("app serve: app serve <ADDR>", b.String())
+	}))
+	t.Run("nearest template wins", x.F(func(x x.X) {
+		c := &xli.Command{
+			Name:         "app",
+			HelpTemplate: template.Must(xli.ParseHelpTemplate(`root`)),
+			Commands: xli.Commands{
+				&xli.Command{
+					Name:         "serve",
+					HelpTemplate: template.Must(xli.ParseHelpTemplate(`serve`)),
+				},
+			},
+		}
+
+		b := &strings.Builder{}
+		c.Writer = b
+		err := c.Run(t.Context(), []string{"serve", "--help"})
+		x.NoError(err)
+		x.Equal("serve", b.String())
+	}))
+	t.Run("flags and commands are grouped by category", x.F(func(x x.X) {
+		c := &xli.Command{
+			Name:  "app",
+			Flags: flg.Flags{&flg.String{Name: "a"}}.WithCategory("net", &flg.String{Name: "b"}),
+			Commands: xli.Commands{
+				&xli.Command{Name: "x"},
+			}.WithCategory("debug", &xli.Command{Name: "y"}),
+		}
+
+		d := xli.NewHelpData(c)
+		x.Len(d.Flags, 2)
+		x.Equal("net", d.Flags[1].Category)
+		x.Equal("b", d.Flags[1].Flags[0].Name)
+		x.Len(d.Commands, 2)
+		x.Equal("debug", d.Commands[1].Category)
+		x.Equal("y", d.Commands[1].Commands[0].Name)
+	}))
+}
+
+func TestHelpFuncs(t *testing.T) {
+	wrap := xli.HelpFuncs["wrap"].(func(int, string) string)
+	indent := xli.HelpFuncs["indent"].(func(int, string) string)
+
+	t.Run("wrap", x.F(func(x x.X) {
+		x.Equal("aaa bbb\nccc", wrap(7, "aaa bbb ccc"))
+		x.Equal("aaa\nbbb", wrap(7, "aaa\nbbb"))
+		x.Equal("aaaaaaaaa\nb", wrap(3, "aaaaaaaaa b"))
+	}))
+	t.Run("indent", x.F(func(x x.X) {
+		x.Equal("  a\n\n  b", indent(2, "a\n\nb"))
+	}))
+}
+
+func TestHelpLayout(t *testing.T) {
+	t.Run("descriptions are aligned to the widest label", x.F(func(x x.X) {
+		t.Setenv("COLUMNS", "80")
+		c := &xli.Command{
+			Name: "app",
+			Flags: flg.Flags{
+				&flg.Switch{Name: "a-very-long-flag-name", Brief: "long"},
+				&flg.Switch{Name: "x", Brief: "short"},
+			},
+		}
+
+		b := &strings.Builder{}
+		err := c.PrintHelp(b)
+		x.NoError(err)
+		x.Contains(b.String(), "\n       --a-very-long-flag-name   long\n")
+		x.Contains(b.String(), "\n       --x                       short\n")
+	}))
+	t.Run("long descriptions are wrapped at the terminal width", x.F(func(x x.X) {
+		t.Setenv("COLUMNS", "30")
+		c := &xli.Command{
+			Name: "app",
+			Commands: xli.Commands{
+				&xli.Command{Name: "serve", Brief: "serve the files in the given directory"},
+			},
+		}
+
+		b := &strings.Builder{}
+		err := c.PrintHelp(b)
+		x.NoError(err)
+		x.Contains(b.String(), "\n"+
+			"    serve  serve the files in\n"+
+			"           the given directory\n")
+	}))
+	t.Run("descriptions keep a minimum width", x.F(func(x x.X) {
+		t.Setenv("COLUMNS", "30")
+		c := &xli.Command{
+			Name: "app",
+			Flags: flg.Flags{
+				&flg.Switch{Name: "a-flag-name-that-is-far-too-long", Brief: "turn on the thing"},
+			},
+		}
+
+		b := &strings.Builder{}
+		err := c.PrintHelp(b)
+		x.NoError(err)
+		x.Contains(b.String(), "--a-flag-name-that-is-far-too-long   turn on the thing\n")
+	}))
+	t.Run("no trailing spaces without a description", x.F(func(x x.X) {
+		c := &xli.Command{
+			Name: "app",
+			Commands: xli.Commands{
+				&xli.Command{Name: "serve"},
+			},
+		}
+
+		b := &strings.Builder{}
+		err := c.PrintHelp(b)
+		x.NoError(err)
+		x.Contains(b.String(), "\n    serve\n")
+	}))
+}
+
+func TestHelpExamples(t *testing.T) {
+	t.Run("examples are prefixed with the command path", x.F(func(x x.X) {
+		c := &xli.Command{
+			Name: "app",
+			Commands: xli.Commands{
+				&xli.Command{
+					Name: "serve",
+					Args: arg.Args{&arg.String{Name: "DIR"}},
+					Examples: []xli.Example{
+						{Brief: "serve the current directory", Cmdline: "."},
+						{Cmdline: "/srv"},
+					},
+				},
+			},
+		}
+
+		b := &strings.Builder{}
+		c.Writer = b
+		err := c.Run(t.Context(), []string{"serve", "--help"})
+		x.NoError(err)
+		x.Contains(b.String(), "\n\nExamples:\n"+
+			"    # serve the current directory\n"+
+			"    app serve .\n"+
+			"\n"+
+			"    app serve /srv\n")
+	}))
+}
+
+func TestCheckExamples(t *testing.T) {
+	newCmd := func(examples ...xli.Example) *xli.Command {
+		return &xli.Command{
+			Name: "app",
+			Commands: xli.Commands{
+				&xli.Command{
+					Name:     "serve",
+					Flags:    flg.Flags{&flg.Int{Name: "port"}},
+					Args:     arg.Args{&arg.String{Name: "DIR"}},
+					Examples: examples,
+				},
+			},
+		}
+	}
+
+	t.Run("valid examples", x.F(func(x x.X) {
+		c := newCmd(
+			xli.Example{Cmdline: "."},
+			xli.Example{Cmdline: "--port 80 /srv"},
+			xli.Example{Cmdline: "'/srv/my site'"},
+		)
+		x.NoError(xli.CheckExamples(c))
+	}))
+	t.Run("badly quoted example", x.F(func(x x.X) {
+		c := newCmd(xli.Example{Cmdline: "'/srv"})
+		err := xli.CheckExamples(c)
+		x.ErrorContains(err, "unterminated single quote")
+	}))
+	t.Run("stale examples", x.F(func(x x.X) {
+		c := newCmd(
+			xli.Example{Cmdline: "--addr=:80 ."},
+			xli.Example{Cmdline: ""},
+		)
+		err := xli.CheckExamples(c)
+		x.True(errors.Is(err, xli.ErrUnknownFlag))
+		x.True(errors.Is(err, xli.ErrNeedArgs))
+		x.ErrorContains(err, `"app serve --addr=:80 ."`)
+	}))
+}
+
+func TestCmdHelp(t *testing.T) {
+	newCmd := func() *xli.Command {
+		return &xli.Command{
+			Name: "app",
+			Commands: xli.Commands{
+				&xli.Command{
+					Name:    "server",
+					Aliases: []string{"srv"},
+					Brief:   "manage servers",
+					Commands: xli.Commands{
+						&xli.Command{
+							Name:  "start",
+							Brief: "start a server",
+							Flags: flg.Flags{&flg.Int{Name: "port"}},
+						},
+						xli.NewCmdHelp(),
+					},
+				},
+				xli.NewCmdHelp(),
+			},
+		}
+	}
+	run := func(args ...string) (string, error) {
+		b := &strings.Builder{}
+		c := newCmd()
+		c.Writer = b
+		err := c.Run(t.Context(), args)
+		return b.String(), err
+	}
+
+	t.Run("help of the command it is mounted in", x.F(func(x x.X) {
+		expected, err := run("--help")
+		x.NoError(err)
+		actual, err := run("help")
+		x.NoError(err)
+		x.Equal(expected, actual)
+	}))
+	t.Run("help of a path with aliases", x.F(func(x x.X) {
+		expected, err := run("server", "start", "--help")
+		x.NoError(err)
+		actual, err := run("help", "srv", "start")
+		x.NoError(err)
+		x.Equal(expected, actual)
+		x.Contains(actual, "app server start [options]")
+	}))
+	t.Run("mounted in a subcommand", x.F(func(x x.X) {
+		expected, err := run("server", "start", "--help")
+		x.NoError(err)
+		actual, err := run("server", "help", "start")
+		x.NoError(err)
+		x.Equal(expected, actual)
+	}))
+	t.Run("unknown command", x.F(func(x x.X) {
+		_, err := run("help", "server", "stop")
+		x.True(errors.Is(err, xli.ErrUnknownCmd))
+
+		var perr xli.ParseError
+		x.True(errors.As(err, &perr))
+		x.Equal(2, perr.Index())
+	}))
+	t.Run("completes subcommands", x.F(func(x x.X) {
+		out := complete(t, newCmd(), "", "", "help")
+		x.Contains(out, "server")
+		x.Contains(out, "help")
+
+		out = complete(t, newCmd(), "", "", "help", "srv")
+		x.Contains(out, "start")
+		x.NotContains(out, "manage servers")
+
+		out = complete(t, newCmd(), "", "", "server", "help")
+		x.Contains(out, "start")
+	}))
+}
+
+func TestHelpAll(t *testing.T) {
+	newCmd := func() *xli.Command {
+		return &xli.Command{
+			Name:  "app",
+			Brief: "does things",
+			Commands: xli.Commands{
+				&xli.Command{
+					Name:  "server",
+					Brief: "manage servers",
+					Commands: xli.Commands{
+						&xli.Command{Name: "start", Brief: "start a server"},
+					}.WithCategory("debug",
+						&xli.Command{Name: "dump", Aliases: []string{"d"}},
+					),
+				},
+				&xli.Command{Name: "secret", Hidden: true},
+				xli.NewCmdHelp(),
+			},
+		}
+	}
+	run := func(args ...string) string {
+		b := &strings.Builder{}
+		c := newCmd()
+		c.Writer = b
+		if err := c.Run(t.Context(), args); err != nil {
+			t.Fatal(err)
+		}
+		return b.String()
+	}
+
+	t.Run("tree", x.F(func(x x.X) {
+		t.Setenv("COLUMNS", "80")
+		x.Equal(""+
+			"app           does things\n"+
+			"  server      manage servers\n"+
+			"    start     start a server\n"+
+			"    debug:\n"+
+			"      dump,d\n"+
+			"  help        Show help for a command\n",
+			run("help", "--all"))
+	}))
+	t.Run("tree of a path", x.F(func(x x.X) {
+		x.Equal(""+
+			"server      manage servers\n"+
+			"  start     start a server\n"+
+			"  debug:\n"+
+			"    dump,d\n",
+			run("help", "--all", "server"))
+	}))
+	t.Run("long", x.F(func(x x.X) {
+		expected := run("--help") + "\n" +
+			run("server", "--help") + "\n" +
+			run("server", "start", "--help") + "\n" +
+			run("server", "dump", "--help") + "\n" +
+			run("help", "--help")
+		x.Equal(expected, run("help", "-a", "-l"))
+	}))
+}
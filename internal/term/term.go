@@ -0,0 +1,58 @@
+// Package term inspects and controls the terminal the command runs in.
+package term
+
+import (
+	"io"
+	"os"
+	"strconv"
+)
+
+// DefaultWidth is the width assumed when it cannot be determined.
+const DefaultWidth = 80
+
+// Width returns the number of columns of the terminal `w` writes to. `$COLUMNS`
+// takes precedence; otherwise the size is queried if `w` is a terminal, and
+// `DefaultWidth` is returned if neither is available.
+func Width(w io.Writer) int {
+	if v, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && v > 0 {
+		return v
+	}
+	if f, ok := w.(interface{ Fd() uintptr }); ok {
+		if v, ok := width(f.Fd()); ok && v > 0 {
+			return v
+		}
+	}
+	return DefaultWidth
+}
+
+// IsTerminal reports whether `v`, a reader or a writer, is a terminal.
+func IsTerminal(v any) bool {
+	f, ok := v.(interface{ Fd() uintptr })
+	if !ok {
+		return false
+	}
+	_, ok = width(f.Fd())
+	return ok
+}
+
+// NoEcho stops the terminal `r` from echoing the input, e.g. while a password
+// is typed. It returns a function that restores the terminal, or false if `r`
+// is not a terminal.
+func NoEcho(r any) (restore func(), ok bool) {
+	f, ok := r.(interface{ Fd() uintptr })
+	if !ok {
+		return nil, false
+	}
+	return noEcho(f.Fd())
+}
+
+// Raw puts the terminal `r` in raw mode, in which the input is read a key at a
+// time, without echo and without signals for keys like Ctrl-C. It returns a
+// function that restores the terminal, or false if `r` is not a terminal.
+func Raw(r any) (restore func(), ok bool) {
+	f, ok := r.(interface{ Fd() uintptr })
+	if !ok {
+		return nil, false
+	}
+	return raw(f.Fd())
+}
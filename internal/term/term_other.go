@@ -0,0 +1,15 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package term
+
+func width(fd uintptr) (int, bool) {
+	return 0, false
+}
+
+func noEcho(fd uintptr) (func(), bool) {
+	return nil, false
+}
+
+func raw(fd uintptr) (func(), bool) {
+	return nil, false
+}
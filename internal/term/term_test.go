@@ -0,0 +1,65 @@
+package term_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/lesomnus/xli/internal/term"
+	"github.com/lesomnus/xli/internal/x"
+)
+
+func TestWidth(t *testing.T) {
+	t.Run("COLUMNS takes precedence", x.F(func(x x.X) {
+		t.Setenv("COLUMNS", "123")
+		x.Equal(123, term.Width(&strings.Builder{}))
+	}))
+	t.Run("invalid COLUMNS is ignored", x.F(func(x x.X) {
+		t.Setenv("COLUMNS", "wide")
+		x.Equal(term.DefaultWidth, term.Width(&strings.Builder{}))
+	}))
+	t.Run("non-terminal falls back to the default", x.F(func(x x.X) {
+		t.Setenv("COLUMNS", "")
+		x.Equal(term.DefaultWidth, term.Width(&strings.Builder{}))
+	}))
+}
+
+func TestIsTerminal(t *testing.T) {
+	t.Run("non-file is not a terminal", x.F(func(x x.X) {
+		x.False(term.IsTerminal(&strings.Builder{}))
+	}))
+	t.Run("regular file is not a terminal", x.F(func(x x.X) {
+		f, err := os.CreateTemp(t.TempDir(), "")
+		x.NoError(err)
+		defer f.Close()
+		x.False(term.IsTerminal(f))
+	}))
+}
+
+func TestNoEcho(t *testing.T) {
+	t.Run("non-terminal", x.F(func(x x.X) {
+		f, err := os.CreateTemp(t.TempDir(), "")
+		x.NoError(err)
+		defer f.Close()
+
+		_, ok := term.NoEcho(f)
+		x.False(ok)
+
+		_, ok = term.NoEcho(strings.NewReader(""))
+		x.False(ok)
+	}))
+}
+
+func TestRaw(t *testing.T) {
+	t.Run("non-terminal", x.F(func(x x.X) {
+		f, err := os.CreateTemp(t.TempDir(), "")
+		x.NoError(err)
+		defer f.Close()
+
+		_, ok := term.Raw(f)
+		x.False(ok)
+
+		_, ok = term.Raw(strings.NewReader(""))
+		x.False(ok)
+	}))
+}
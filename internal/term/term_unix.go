@@ -0,0 +1,57 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package term
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+type winsize struct {
+	row    uint16
+	col    uint16
+	xpixel uint16
+	ypixel uint16
+}
+
+func width(fd uintptr) (int, bool) {
+	ws := winsize{}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&ws)))
+	if errno != 0 {
+		return 0, false
+	}
+	return int(ws.col), true
+}
+
+func noEcho(fd uintptr) (func(), bool) {
+	return setTermios(fd, func(t *syscall.Termios) {
+		t.Lflag &^= syscall.ECHO
+	})
+}
+
+func raw(fd uintptr) (func(), bool) {
+	return setTermios(fd, func(t *syscall.Termios) {
+		t.Iflag &^= syscall.ICRNL | syscall.IXON
+		t.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+		t.Cc[syscall.VMIN] = 1
+		t.Cc[syscall.VTIME] = 0
+	})
+}
+
+// setTermios changes the attributes of the terminal `fd` by `f` and returns a
+// function that restores them.
+func setTermios(fd uintptr, f func(t *syscall.Termios)) (func(), bool) {
+	t := syscall.Termios{}
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlGetTermios, uintptr(unsafe.Pointer(&t))); errno != 0 {
+		return nil, false
+	}
+
+	u := t
+	f(&u)
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlSetTermios, uintptr(unsafe.Pointer(&u))); errno != 0 {
+		return nil, false
+	}
+	return func() {
+		syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlSetTermios, uintptr(unsafe.Pointer(&t)))
+	}, true
+}
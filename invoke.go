@@ -0,0 +1,97 @@
+package xli
+
+import (
+	"context"
+	"io"
+
+	"github.com/lesomnus/xli/arg"
+	"github.com/lesomnus/xli/flg"
+)
+
+// Invocation holds the IO of a single `Invoke`. Fields left nil fall back to
+// the ones set on the command, and then to the standard streams.
+type Invocation struct {
+	io.ReadCloser
+	io.Writer
+	ErrWriter io.Writer
+
+	// NoResponseFiles turns off the expansion of "@FILE" words, as
+	// `Command.NoResponseFiles` does.
+	NoResponseFiles bool
+	// NoFileValues stops the flags from reading their values from files, file
+	// descriptors, and the environment, such as "@FILE", "@fd:N", and
+	// "@env:NAME" given to a `flg.Secret`; see `flg.WithoutFileValues`.
+	NoFileValues bool
+}
+
+// Invoke runs `args` like `Run`, but on a copy of `c` and its descendants with
+// the IO of `inv`. `c` is left untouched, so the same tree can be invoked
+// concurrently, e.g. by a server running a command for each request.
+//
+// Handlers are given the copies, so `flg.Get` and `arg.Get` on the command a
+// handler receives return the values of its own invocation. Flags and
+// arguments declared outside of the tree must not be read directly, and state
+// shared by handlers, such as variables their closures write to, must be
+// guarded by the handlers themselves.
+//
+// A command line runs with the access of the process: by default, "@FILE"
+// reads a response file and a `flg.Secret` reads "@FILE", "@fd:N", and
+// "@env:NAME", so a command line from an untrusted user can read any file the
+// process can, the descriptors it holds, and its environment. Set
+// `NoResponseFiles` and `NoFileValues` of `inv` for such command lines.
+//
+// The flags and arguments are copied with their `Clone() flg.Flag` and
+// `Clone() arg.Arg` methods, which those of the packages `flg` and `arg`
+// have. The ones without such a method are shared by the copies, so a tree
+// holding them must not be invoked concurrently.
+func (c *Command) Invoke(ctx context.Context, args []string, inv Invocation) error {
+	v := c.clone()
+	if inv.ReadCloser != nil {
+		v.ReadCloser = inv.ReadCloser
+		v.io_set &^= ioReader
+	}
+	if inv.Writer != nil {
+		v.Writer = inv.Writer
+		v.io_set &^= ioWriter
+	}
+	if inv.ErrWriter != nil {
+		v.ErrWriter = inv.ErrWriter
+		v.io_set &^= ioErrWriter
+	}
+	if inv.NoResponseFiles {
+		v.NoResponseFiles = true
+	}
+	if inv.NoFileValues {
+		ctx = flg.WithoutFileValues(ctx)
+	}
+	return v.Run(ctx, args)
+}
+
+// clone returns a copy of `c` and its descendants without the state of a
+// previous run. Handlers, templates, stores, and the flags and arguments that
+// cannot be cloned are shared.
+func (c *Command) clone() *Command {
+	v := *c
+	v.parent = nil
+	v.clearIO()
+
+	v.Flags = make(flg.Flags, len(c.Flags))
+	for i, f := range c.Flags {
+		if u, ok := f.(interface{ Clone() flg.Flag }); ok {
+			f = u.Clone()
+		}
+		v.Flags[i] = f
+	}
+	v.Args = make(arg.Args, len(c.Args))
+	for i, a := range c.Args {
+		if u, ok := a.(interface{ Clone() arg.Arg }); ok {
+			a = u.Clone()
+		}
+		v.Args[i] = a
+	}
+	v.Commands = make(Commands, len(c.Commands))
+	for i, u := range c.Commands {
+		v.Commands[i] = u.clone()
+	}
+	return &v
+}
@@ -0,0 +1,156 @@
+package xli_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lesomnus/xli"
+	"github.com/lesomnus/xli/arg"
+	"github.com/lesomnus/xli/flg"
+	"github.com/lesomnus/xli/internal/x"
+)
+
+func TestInvoke(t *testing.T) {
+	newCmd := func() *xli.Command {
+		return &xli.Command{
+			Name: "app",
+			Flags: flg.Flags{
+				&flg.Int{Name: "times"},
+			},
+			Commands: xli.Commands{
+				xli.NewCmdHelp(),
+				&xli.Command{
+					Name:  "echo",
+					Flags: flg.Flags{&flg.Switch{Name: "upper"}},
+					Args:  arg.Args{&arg.String{Name: "WORD"}},
+					Handler: xli.OnRun(func(ctx context.Context, cmd *xli.Command, next xli.Next) error {
+						n, ok := flg.Get[int](cmd.Parent(), "times")
+						if !ok {
+							n = 1
+						}
+						v := arg.MustGet[string](cmd, "WORD")
+						if upper, _ := flg.Get[bool](cmd, "upper"); upper {
+							v = strings.ToUpper(v)
+						}
+						for range n {
+							cmd.Println(v)
+						}
+						return next(ctx)
+					}),
+				},
+			},
+		}
+	}
+
+	t.Run("declaration is left untouched", x.F(func(x x.X) {
+		c := newCmd()
+		o := &strings.Builder{}
+		err := c.Invoke(t.Context(), []string{"--times=2", "echo", "--upper", "foo"}, xli.Invocation{Writer: o})
+		x.NoError(err)
+		x.Equal("FOO\nFOO\n", o.String())
+
+		x.Equal(0, c.Flags.Get("times").Count())
+		x.Equal(0, c.Commands[1].Flags.Get("upper").Count())
+		_, ok := arg.Get[string](c.Commands[1], "WORD")
+		x.False(ok)
+		x.Nil(c.Writer)
+		x.Nil(c.Commands[1].Writer)
+	}))
+	t.Run("IO of the command is the fallback", x.F(func(x x.X) {
+		c := newCmd()
+		o := &strings.Builder{}
+		c.Writer = o
+		err := c.Invoke(t.Context(), []string{"echo", "foo"}, xli.Invocation{})
+		x.NoError(err)
+		x.Equal("foo\n", o.String())
+	}))
+	t.Run("flags without Clone are shared", x.F(func(x x.X) {
+		// Embedding the interface hides the `Clone` of `flg.String`.
+		shared := struct{ flg.Flag }{&flg.String{Name: "foo"}}
+		cloned := &flg.String{Name: "bar"}
+		c := &xli.Command{
+			Name:  "app",
+			Flags: flg.Flags{shared, cloned},
+			Handler: xli.OnRun(func(ctx context.Context, cmd *xli.Command, next xli.Next) error {
+				x.True(cmd.Flags[0] == shared)
+				x.True(cmd.Flags[1] != cloned)
+				return next(ctx)
+			}),
+		}
+		err := c.Invoke(t.Context(), []string{"--foo=a", "--bar=b"}, xli.Invocation{})
+		x.NoError(err)
+		x.Equal(1, shared.Count())
+		x.Equal(0, cloned.Count())
+	}))
+	t.Run("files are not read for untrusted command lines", x.F(func(x x.X) {
+		p := filepath.Join(t.TempDir(), "f")
+		x.NoError(os.WriteFile(p, []byte("foo"), 0o600))
+
+		c := newCmd()
+		c.Flags = append(c.Flags, &flg.Secret{Name: "password"})
+		inv := xli.Invocation{NoResponseFiles: true, NoFileValues: true}
+
+		o := &strings.Builder{}
+		inv.Writer = o
+		err := c.Invoke(t.Context(), []string{"echo", "@" + p}, inv)
+		x.NoError(err)
+		x.Equal("@"+p+"\n", o.String())
+
+		err = c.Invoke(t.Context(), []string{"--password=@" + p, "echo", "a"}, inv)
+		x.True(errors.Is(err, xli.ErrInvalidFlag))
+		x.True(errors.Is(err, flg.ErrFileValue))
+
+		err = c.Invoke(t.Context(), []string{"--password=@fd:0", "echo", "a"}, inv)
+		x.True(errors.Is(err, flg.ErrFileValue))
+
+		err = c.Invoke(t.Context(), []string{"--password=@@x", "echo", "a"}, inv)
+		x.NoError(err)
+	}))
+	t.Run("Invoke is safe for concurrent use", func(t *testing.T) {
+		c := newCmd()
+
+		const n = 32
+		outs := make([]*strings.Builder, n)
+		errs := make([]error, n)
+		wg := sync.WaitGroup{}
+		for i := range n {
+			outs[i] = &strings.Builder{}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				args := []string{fmt.Sprintf("--times=%d", i%3+1), "echo"}
+				if i%2 == 0 {
+					args = append(args, "--upper")
+				}
+				args = append(args, fmt.Sprintf("w%d", i))
+				if i%5 == 0 {
+					args = []string{"help", "echo"}
+				}
+				errs[i] = c.Invoke(t.Context(), args, xli.Invocation{Writer: outs[i], ErrWriter: outs[i]})
+			}()
+		}
+		wg.Wait()
+
+		x := x.New(t)
+		for i := range n {
+			x.NoError(errs[i])
+			if i%5 == 0 {
+				x.Contains(outs[i].String(), "app echo")
+				continue
+			}
+
+			v := fmt.Sprintf("w%d", i)
+			if i%2 == 0 {
+				v = strings.ToUpper(v)
+			}
+			x.Equal(strings.Repeat(v+"\n", i%3+1), outs[i].String())
+		}
+	})
+}
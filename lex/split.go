@@ -0,0 +1,281 @@
+package lex
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var ErrUnterminated = errors.New("unterminated")
+
+// Cursor is the word of a command line a position is in.
+type Cursor struct {
+	// Index is the index of the word. It is the number of words before the
+	// position if the position is between words.
+	Index int
+	// Start is the byte offset of the word in the line, including its
+	// opening quote. It is the position itself if it is between words.
+	Start int
+	// Prefix is the part of the word before the position, unquoted.
+	Prefix string
+	// Quote is the quote open at the position: '\'', '"', '$' for $'...',
+	// or 0 if there is none.
+	Quote byte
+}
+
+// Split splits the command line `s` into words as a POSIX shell does, without
+// expanding anything. Words are separated by blanks and newlines, and may be
+// quoted:
+//
+//	'...'   taken literally
+//	"..."   a backslash escapes $, `, ", \, and a newline
+//	$'...'  a backslash escapes as in C, e.g. \n, \t, \x41, \u00e9
+//	\c      outside quotes, the character c itself
+//
+// A backslash before a newline joins the lines, and a "#" at the start of a
+// word comments out the rest of the line.
+func Split(s string) ([]string, error) {
+	vs, _, err := split(s, -1)
+	return vs, err
+}
+
+// SplitCursor is `Split` that also reports the word the byte offset `pos` of
+// `s` is in. A quote left open at the end of `s` is not an error if `pos` is
+// at the end, so a word can be completed while it is typed.
+func SplitCursor(s string, pos int) ([]string, Cursor, error) {
+	return split(s, pos)
+}
+
+func split(s string, pos int) ([]string, Cursor, error) {
+	vs := []string{}
+	b := &strings.Builder{}
+	in_word := false
+	start := 0
+	var quote byte
+
+	cur := Cursor{Index: -1}
+	mark := func(i int) {
+		if pos < 0 || i < pos || cur.Index >= 0 {
+			return
+		}
+		if in_word {
+			cur = Cursor{Index: len(vs), Start: start, Prefix: b.String(), Quote: quote}
+		} else {
+			cur = Cursor{Index: len(vs), Start: pos}
+		}
+	}
+	begin := func(i int) {
+		if !in_word {
+			in_word = true
+			start = i
+		}
+	}
+
+	for i := 0; i < len(s); i++ {
+		mark(i)
+		c := s[i]
+		switch quote {
+		case '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				b.WriteByte(c)
+			}
+			continue
+
+		case '"':
+			switch {
+			case c == '"':
+				quote = 0
+			case c == '\\' && i+1 < len(s) && strings.IndexByte("$`\"\\\n", s[i+1]) >= 0:
+				i++
+				if s[i] != '\n' {
+					b.WriteByte(s[i])
+				}
+			default:
+				b.WriteByte(c)
+			}
+			continue
+
+		case '$':
+			switch {
+			case c == '\'':
+				quote = 0
+			case c == '\\' && i+1 < len(s):
+				i += unescape(b, s[i+1:])
+			default:
+				b.WriteByte(c)
+			}
+			continue
+		}
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if in_word {
+				vs = append(vs, b.String())
+				b.Reset()
+				in_word = false
+			}
+
+		case c == '#' && !in_word:
+			for i+1 < len(s) && s[i+1] != '\n' {
+				i++
+			}
+
+		case c == '\'' || c == '"':
+			begin(i)
+			quote = c
+
+		case c == '$' && i+1 < len(s) && s[i+1] == '\'':
+			begin(i)
+			quote = '$'
+			i++
+
+		case c == '\\':
+			if i+1 == len(s) {
+				if pos == len(s) {
+					begin(i)
+					continue
+				}
+				return nil, cur, fmt.Errorf("%w backslash", ErrUnterminated)
+			}
+			i++
+			if s[i] == '\n' {
+				continue
+			}
+			begin(i - 1)
+			b.WriteByte(s[i])
+
+		default:
+			begin(i)
+			b.WriteByte(c)
+		}
+	}
+	mark(len(s))
+
+	if quote != 0 && pos != len(s) {
+		kind := map[byte]string{'\'': "single", '"': "double", '$': "$'...'"}[quote]
+		return nil, cur, fmt.Errorf("%w %s quote", ErrUnterminated, kind)
+	}
+	if in_word {
+		vs = append(vs, b.String())
+	}
+	return vs, cur, nil
+}
+
+// unescape writes the character the escape sequence at the start of `s`
+// stands for in $'...' and returns the number of bytes of the sequence, not
+// counting the backslash.
+func unescape(b *strings.Builder, s string) int {
+	c := s[0]
+	if v, ok := map[byte]byte{
+		'a': '\a', 'b': '\b', 'e': 0x1b, 'E': 0x1b, 'f': '\f', 'n': '\n',
+		'r': '\r', 't': '\t', 'v': '\v', '\\': '\\', '\'': '\'', '"': '"', '?': '?',
+	}[c]; ok {
+		b.WriteByte(v)
+		return 1
+	}
+
+	// digits parses up to `n` digits from `s[from:]` and returns the value and
+	// the index after them.
+	digits := func(base int, n int, from int) (uint64, int) {
+		i := from
+		for ; i < len(s) && i-from < n; i++ {
+			if _, err := strconv.ParseUint(s[i:i+1], base, 8); err != nil {
+				break
+			}
+		}
+		v, _ := strconv.ParseUint(s[from:i], base, 32)
+		return v, i
+	}
+
+	switch {
+	case c >= '0' && c <= '7':
+		v, n := digits(8, 3, 0)
+		b.WriteByte(byte(v))
+		return n
+	case c == 'x':
+		if v, n := digits(16, 2, 1); n > 1 {
+			b.WriteByte(byte(v))
+			return n
+		}
+	case c == 'u' || c == 'U':
+		l := 4
+		if c == 'U' {
+			l = 8
+		}
+		if v, n := digits(16, l, 1); n > 1 {
+			b.WriteRune(rune(v))
+			return n
+		}
+	case c == 'c' && len(s) > 1:
+		b.WriteByte(s[1] & 0x1f)
+		return 2
+	}
+
+	// Unknown sequences are kept as they are.
+	b.WriteByte('\\')
+	b.WriteByte(c)
+	return 1
+}
+
+// Quote quotes `v` so that `Split` gives it back as a word, leaving it as is
+// if it needs no quotes. Words with control characters are quoted as $'...'.
+func Quote(v string) string {
+	if v == "" {
+		return "''"
+	}
+
+	plain := true
+	for _, r := range v {
+		if r < 0x20 || r == 0x7f || r == utf8.RuneError {
+			return quoteANSI(v)
+		}
+		if !(unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("@%_+=:,./-", r)) {
+			plain = false
+		}
+	}
+	if plain {
+		return v
+	}
+	return "'" + strings.ReplaceAll(v, "'", `'\''`) + "'"
+}
+
+func quoteANSI(v string) string {
+	b := &strings.Builder{}
+	b.WriteString("$'")
+	for i := 0; i < len(v); {
+		r, n := utf8.DecodeRuneInString(v[i:])
+		switch {
+		case r == '\\' || r == '\'':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r < 0x20 || r == 0x7f || (r == utf8.RuneError && n == 1):
+			fmt.Fprintf(b, `\x%02x`, v[i])
+		default:
+			b.WriteString(v[i : i+n])
+		}
+		i += n
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
+
+// Join quotes each of `vs` as `Quote` does and joins them with spaces, so the
+// line can be pasted into a shell or given to `Split` to get `vs` back.
+func Join(vs []string) string {
+	ws := make([]string, len(vs))
+	for i, v := range vs {
+		ws[i] = Quote(v)
+	}
+	return strings.Join(ws, " ")
+}
@@ -0,0 +1,117 @@
+package lex_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lesomnus/xli/internal/x"
+	"github.com/lesomnus/xli/lex"
+)
+
+func TestSplit(t *testing.T) {
+	tcs := []struct {
+		desc  string
+		input string
+		words []string
+	}{
+		{"empty", "", []string{}},
+		{"blanks", " \t\n", []string{}},
+		{"words", "foo  bar\tbaz\nqux", []string{"foo", "bar", "baz", "qux"}},
+		{"single quotes", `'a b' 'c\d' ''`, []string{"a b", `c\d`, ""}},
+		{"double quotes", `"a b" "c\"d" "e\f" "$\$"`, []string{"a b", `c"d`, `e\f`, "$$"}},
+		{"backslash", `a\ b \'c \\`, []string{"a b", "'c", `\`}},
+		{"concatenated", `a'b'"c"$'d'`, []string{"abcd"}},
+		{"ansi-c", `$'a\nb\t\x41\101é\'\\\q'`, []string{"a\nb\tAAé'\\\\q"}},
+		{"line continuation", "foo \\\nbar ba\\\nz", []string{"foo", "bar", "baz"}},
+		{"comment", "foo # bar baz\nqux a#b", []string{"foo", "qux", "a#b"}},
+		{"dollar", "$HOME $", []string{"$HOME", "$"}},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.desc, x.F(func(x x.X) {
+			vs, err := lex.Split(tc.input)
+			x.NoError(err)
+			x.Equal(tc.words, vs)
+		}))
+	}
+
+	t.Run("unterminated", x.F(func(x x.X) {
+		for _, v := range []string{`'a`, `"a`, `$'a`, `a\`} {
+			_, err := lex.Split(v)
+			x.True(errors.Is(err, lex.ErrUnterminated), v)
+		}
+		_, err := lex.Split(`'a`)
+		x.ErrorContains(err, "unterminated single quote")
+	}))
+}
+
+func TestSplitCursor(t *testing.T) {
+	tcs := []struct {
+		desc   string
+		input  string
+		pos    int
+		words  []string
+		cursor lex.Cursor
+	}{
+		{"empty", "", 0, []string{}, lex.Cursor{}},
+		{"end of word", "foo ba", 6, []string{"foo", "ba"}, lex.Cursor{Index: 1, Start: 4, Prefix: "ba"}},
+		{"after blank", "foo ", 4, []string{"foo"}, lex.Cursor{Index: 1, Start: 4}},
+		{"middle of word", "foo bar", 5, []string{"foo", "bar"}, lex.Cursor{Index: 1, Start: 4, Prefix: "b"}},
+		{"between words", "foo  bar", 4, []string{"foo", "bar"}, lex.Cursor{Index: 1, Start: 4}},
+		{"open quote", "foo 'a b", 8, []string{"foo", "a b"}, lex.Cursor{Index: 1, Start: 4, Prefix: "a b", Quote: '\''}},
+		{"closed quote", `foo "a b"c`, 10, []string{"foo", "a bc"}, lex.Cursor{Index: 1, Start: 4, Prefix: "a bc"}},
+		{"trailing backslash", `foo a\`, 6, []string{"foo", "a"}, lex.Cursor{Index: 1, Start: 4, Prefix: "a"}},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.desc, x.F(func(x x.X) {
+			vs, cur, err := lex.SplitCursor(tc.input, tc.pos)
+			x.NoError(err)
+			x.Equal(tc.words, vs)
+			x.Equal(tc.cursor, cur)
+		}))
+	}
+
+	t.Run("open quote before the cursor", x.F(func(x x.X) {
+		_, _, err := lex.SplitCursor("'a b", 2)
+		x.True(errors.Is(err, lex.ErrUnterminated))
+	}))
+}
+
+func TestQuote(t *testing.T) {
+	tcs := []struct {
+		input  string
+		quoted string
+	}{
+		{"foo", "foo"},
+		{"--port=8080", "--port=8080"},
+		{"a/b.c,d:e@f%g+h_i", "a/b.c,d:e@f%g+h_i"},
+		{"한글", "한글"},
+		{"", "''"},
+		{"a b", "'a b'"},
+		{"it's", `'it'\''s'`},
+		{"$HOME", "'$HOME'"},
+		{"#a", "'#a'"},
+		{"a\nb'\\", `$'a\nb\'\\'`},
+		{"\x00\x1b\xff", `$'\x00\x1b\xff'`},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.input, x.F(func(x x.X) {
+			x.Equal(tc.quoted, lex.Quote(tc.input))
+
+			vs, err := lex.Split(tc.quoted)
+			x.NoError(err)
+			x.Equal([]string{tc.input}, vs)
+		}))
+	}
+}
+
+func TestJoin(t *testing.T) {
+	t.Run("round trip", x.F(func(x x.X) {
+		vs := []string{"app", "--name", "John Doe", "", "it's", "a\tb"}
+		line := lex.Join(vs)
+		x.Equal(`app --name 'John Doe' '' 'it'\''s' $'a\tb'`, line)
+
+		ws, err := lex.Split(line)
+		x.NoError(err)
+		x.Equal(vs, ws)
+	}))
+}
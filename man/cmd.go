@@ -0,0 +1,34 @@
+package man
+
+import (
+	"context"
+	"os"
+
+	"github.com/lesomnus/xli"
+	"github.com/lesomnus/xli/arg"
+)
+
+// NewCmd returns a hidden "man" command that writes the pages of the whole
+// tree it is mounted in into the directory given as its argument, or the
+// current directory.
+func NewCmd(opts Options) *xli.Command {
+	dir := "."
+	return &xli.Command{
+		Name:   "man",
+		Brief:  "Generate man pages",
+		Hidden: true,
+		Args: arg.Args{
+			&arg.String{Name: "DIR", Optional: true, Brief: "directory to write the pages to", Default: &dir},
+		},
+		Handler: xli.OnRun(func(ctx context.Context, cmd *xli.Command, next xli.Next) error {
+			dir := arg.MustGet[string](cmd, "DIR")
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return err
+			}
+			if err := Generate(dir, cmd.Root(), opts); err != nil {
+				return err
+			}
+			return next(ctx)
+		}),
+	}
+}
@@ -0,0 +1,276 @@
+// Package man generates roff man(7) pages from a command tree.
+package man
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/lesomnus/xli"
+	"github.com/lesomnus/xli/arg"
+)
+
+// Options are the fields of the page header. They are left empty when not
+// given, except `Section` which defaults to "1".
+type Options struct {
+	Section string
+	// Date is the date of the last change, e.g. "2026-10-19".
+	Date string
+	// Source is the product the page belongs to, e.g. "mytool 1.2.0".
+	Source string
+	// Manual is the title of the manual, e.g. "mytool Manual".
+	Manual string
+}
+
+func (o Options) section() string {
+	if o.Section == "" {
+		return "1"
+	}
+	return o.Section
+}
+
+// PageName returns the name of the page of the last command in `path`, such as
+// "mytool-server-start" for "mytool server start".
+func PageName(path []*xli.Command) string {
+	vs := make([]string, len(path))
+	for i, c := range path {
+		vs[i] = c.Name
+	}
+	return strings.Join(vs, "-")
+}
+
+// Generate writes a page for `root` and each of its descendants that is not
+// hidden into `dir`, named like "mytool-server-start.1".
+func Generate(dir string, root *xli.Command, opts Options) error {
+	return xli.Walk(root, func(path []*xli.Command) error {
+		if isHidden(path) {
+			return nil
+		}
+
+		name := fmt.Sprintf("%s.%s", PageName(path), opts.section())
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+
+		err = Render(f, path, opts)
+		return errors.Join(err, f.Close())
+	})
+}
+
+// Render writes the page of the last command in `path`, where `path` holds the
+// commands from the root as `xli.Walk` gives.
+func Render(w io.Writer, path []*xli.Command, opts Options) error {
+	c := path[len(path)-1]
+	d := xli.NewHelpData(c)
+	name := PageName(path)
+
+	p := &page{}
+	p.macro("TH", strings.ToUpper(name), opts.section(), opts.Date, opts.Source, opts.Manual)
+
+	p.macro("SH", "NAME")
+	if c.Brief == "" {
+		p.text(name)
+	} else {
+		p.text(name + " - " + c.Brief)
+	}
+
+	p.macro("SH", "SYNOPSIS")
+	p.line(synopsis(c))
+
+	if c.Synop != "" || c.Brief != "" {
+		p.macro("SH", "DESCRIPTION")
+		if c.Synop != "" {
+			p.paragraphs(c.Synop)
+		} else {
+			p.paragraphs(c.Brief)
+		}
+	}
+
+	if slices.ContainsFunc(d.Args, func(a *arg.Info) bool { return a.Brief != "" }) {
+		p.macro("SH", "ARGUMENTS")
+		for _, a := range d.Args {
+			if a.Brief == "" {
+				continue
+			}
+			p.macro("TP")
+			p.line(fmt.Sprintf(`\fI%s\fR`, escape(a.Usage.String())))
+			desc := a.Brief
+			if a.HasDefault {
+				desc += fmt.Sprintf(" (default: %s)", a.Default)
+			}
+			p.text(desc)
+		}
+	}
+
+	if len(d.Flags) > 0 {
+		p.macro("SH", "OPTIONS")
+		for _, g := range d.Flags {
+			if g.Category != "" {
+				p.macro("SS", g.Category)
+			}
+			for _, f := range g.Flags {
+				p.macro("TP")
+				label := fmt.Sprintf(`\fB\-\-%s\fR`, escape(f.Name))
+				if f.Alias != 0 {
+					label = fmt.Sprintf(`\fB\-%s\fR, %s`, escape(string(f.Alias)), label)
+				}
+				if f.Type != "" {
+					label += fmt.Sprintf(`=\fI%s\fR`, escape(f.Type))
+				}
+				p.line(label)
+
+				notes := []string{}
+				if f.Required {
+					notes = append(notes, "required")
+				}
+				if f.HasDefault {
+					notes = append(notes, "default: "+f.Default)
+				}
+				desc := f.Brief
+				if len(notes) > 0 {
+					desc = strings.TrimSpace(fmt.Sprintf("%s (%s)", desc, strings.Join(notes, ", ")))
+				}
+				if desc != "" {
+					p.text(desc)
+				}
+			}
+		}
+	}
+
+	if len(d.Commands) > 0 {
+		p.macro("SH", "COMMANDS")
+		for _, g := range d.Commands {
+			if g.Category != "" {
+				p.macro("SS", g.Category)
+			}
+			for _, v := range g.Commands {
+				p.macro("TP")
+				p.line(fmt.Sprintf(`\fB%s\fR`, escape(v.Name)))
+				if v.Brief != "" {
+					p.text(v.Brief)
+				}
+			}
+		}
+	}
+
+	if len(d.Examples) > 0 {
+		p.macro("SH", "EXAMPLES")
+		for _, v := range d.Examples {
+			if v.Brief != "" {
+				p.text(v.Brief)
+			}
+			p.macro("PP")
+			p.macro("RS", "4")
+			p.macro("EX")
+			p.text(v.Cmdline)
+			p.macro("EE")
+			p.macro("RE")
+		}
+	}
+
+	refs := []string{}
+	if len(path) > 1 {
+		refs = append(refs, PageName(path[:len(path)-1]))
+	}
+	for _, v := range c.Commands.Visible() {
+		refs = append(refs, PageName(append(path[:len(path):len(path)], v)))
+	}
+	if len(refs) > 0 {
+		p.macro("SH", "SEE ALSO")
+		for i, ref := range refs {
+			sep := ""
+			if i < len(refs)-1 {
+				sep = ","
+			}
+			p.line(fmt.Sprintf(`\fB%s\fR(%s)%s`, escape(ref), opts.section(), sep))
+		}
+	}
+
+	_, err := io.WriteString(w, p.String())
+	return err
+}
+
+// synopsis returns the usage line with command names in bold and arguments
+// in italic.
+func synopsis(c *xli.Command) string {
+	vs := []string{}
+	for _, v := range c.Tree() {
+		vs = append(vs, fmt.Sprintf(`\fB%s\fR`, escape(v.Name)))
+		for _, a := range v.Args {
+			vs = append(vs, fmt.Sprintf(`\fI%s\fR`, escape(a.Info().Usage.String())))
+		}
+	}
+	if len(c.Flags) > 0 {
+		vs = append(vs, `[\fIoptions\fR]`)
+	}
+	if len(c.Commands.Visible()) > 0 {
+		vs = append(vs, `[\fIcommand\fR]`)
+	}
+	return strings.Join(vs, " ")
+}
+
+func isHidden(path []*xli.Command) bool {
+	for _, c := range path {
+		if c.Hidden {
+			return true
+		}
+	}
+	return false
+}
+
+type page struct {
+	strings.Builder
+}
+
+// macro writes a request line such as `.SH "SEE ALSO"`.
+func (p *page) macro(name string, args ...string) {
+	p.WriteString("." + name)
+	for _, v := range args {
+		p.WriteString(` "` + strings.ReplaceAll(escape(v), `"`, `\(dq`) + `"`)
+	}
+	p.WriteByte('\n')
+}
+
+// line writes a line of roff as is.
+func (p *page) line(v string) {
+	p.WriteString(v)
+	p.WriteByte('\n')
+}
+
+// text writes plain text.
+func (p *page) text(v string) {
+	for _, l := range strings.Split(v, "\n") {
+		p.line(escapeLine(l))
+	}
+}
+
+// paragraphs writes plain text in which blank lines separate paragraphs.
+func (p *page) paragraphs(v string) {
+	for i, para := range strings.Split(strings.TrimSpace(v), "\n\n") {
+		if i > 0 {
+			p.macro("PP")
+		}
+		p.text(para)
+	}
+}
+
+// escape escapes characters that are special to roff within a line.
+func escape(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\e`)
+	v = strings.ReplaceAll(v, "-", `\-`)
+	return v
+}
+
+// escapeLine escapes `v` so it is not taken as a request line.
+func escapeLine(v string) string {
+	v = escape(v)
+	if strings.HasPrefix(v, ".") || strings.HasPrefix(v, "'") {
+		v = `\&` + v
+	}
+	return v
+}
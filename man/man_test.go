@@ -0,0 +1,118 @@
+package man_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lesomnus/xli"
+	"github.com/lesomnus/xli/arg"
+	"github.com/lesomnus/xli/flg"
+	"github.com/lesomnus/xli/internal/x"
+	"github.com/lesomnus/xli/man"
+)
+
+func newCmd() *xli.Command {
+	port := 8080
+	return &xli.Command{
+		Name:  "mytool",
+		Brief: "does things",
+		Commands: xli.Commands{
+			&xli.Command{
+				Name:  "server",
+				Brief: "manage servers",
+				Commands: xli.Commands{
+					&xli.Command{
+						Name:  "start",
+						Brief: "start a server",
+						Synop: "Starts a server.\n\nIt runs in the foreground.",
+						Flags: flg.Flags{
+							&flg.Int{Name: "port", Alias: 'p', Brief: "port to listen", Default: &port},
+							&flg.String{Category: "Auth", Name: "token", Brief: "access token", Required: true},
+						},
+						Args: arg.Args{
+							&arg.String{Name: "NAME", Brief: "name of the server"},
+						},
+					},
+				},
+			},
+			&xli.Command{Name: "debug", Hidden: true},
+			man.NewCmd(man.Options{}),
+		},
+	}
+}
+
+func TestRender(t *testing.T) {
+	render := func(names ...string) string {
+		path := []*xli.Command{}
+		xli.Walk(newCmd(), func(p []*xli.Command) error {
+			if len(p) == len(names) && man.PageName(p) == strings.Join(names, "-") {
+				path = p
+			}
+			return nil
+		})
+
+		b := &strings.Builder{}
+		err := man.Render(b, path, man.Options{Date: "2026-10-19", Source: "mytool 1.0"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b.String()
+	}
+
+	t.Run("leaf", x.F(func(x x.X) {
+		x.Equal(`.TH "MYTOOL\-SERVER\-START" "1" "2026\-10\-19" "mytool 1.0" ""
+.SH "NAME"
+mytool\-server\-start \- start a server
+.SH "SYNOPSIS"
+\fBmytool\fR \fBserver\fR \fBstart\fR \fI<NAME>\fR [\fIoptions\fR]
+.SH "DESCRIPTION"
+Starts a server.
+.PP
+It runs in the foreground.
+.SH "ARGUMENTS"
+.TP
+\fI<NAME>\fR
+name of the server
+.SH "OPTIONS"
+.TP
+\fB\-p\fR, \fB\-\-port\fR=\fIint\fR
+port to listen (default: 8080)
+.SS "Auth"
+.TP
+\fB\-\-token\fR=\fIstring\fR
+access token (required)
+.SH "SEE ALSO"
+\fBmytool\-server\fR(1)
+`, render("mytool", "server", "start"))
+	}))
+	t.Run("root links to visible children only", x.F(func(x x.X) {
+		out := render("mytool")
+		x.Contains(out, ".SH \"COMMANDS\"\n.TP\n\\fBserver\\fR\nmanage servers\n.SH")
+		x.Contains(out, ".SH \"SEE ALSO\"\n\\fBmytool\\-server\\fR(1)\n")
+		x.NotContains(out, "debug")
+		x.NotContains(out, "\\fBman\\fR")
+	}))
+}
+
+func TestGenerate(t *testing.T) {
+	t.Run("via the hidden command", x.F(func(x x.X) {
+		dir := t.TempDir()
+		err := newCmd().Run(t.Context(), []string{"man", dir})
+		x.NoError(err)
+
+		es, err := os.ReadDir(dir)
+		x.NoError(err)
+
+		names := []string{}
+		for _, e := range es {
+			names = append(names, e.Name())
+		}
+		x.Equal([]string{"mytool-server-start.1", "mytool-server.1", "mytool.1"}, names)
+
+		b, err := os.ReadFile(filepath.Join(dir, "mytool-server.1"))
+		x.NoError(err)
+		x.Contains(string(b), "\\fBmytool\\fR(1),\n\\fBmytool\\-server\\-start\\fR(1)\n")
+	}))
+}
@@ -0,0 +1,179 @@
+// Package markdown generates Markdown reference pages from a command tree.
+//
+// The output depends only on the tree, so it can be checked in and compared
+// against in tests.
+package markdown
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lesomnus/xli"
+)
+
+// PageName returns the file name of the page of the last command in `path`,
+// such as "mytool-server-start.md" for "mytool server start".
+func PageName(path []*xli.Command) string {
+	vs := make([]string, len(path))
+	for i, c := range path {
+		vs[i] = c.Name
+	}
+	return strings.Join(vs, "-") + ".md"
+}
+
+// Generate writes a page for `root` and each of its descendants that is not
+// hidden into `dir`.
+func Generate(dir string, root *xli.Command) error {
+	return xli.Walk(root, func(path []*xli.Command) error {
+		if isHidden(path) {
+			return nil
+		}
+
+		f, err := os.Create(filepath.Join(dir, PageName(path)))
+		if err != nil {
+			return err
+		}
+
+		err = Render(f, path)
+		return errors.Join(err, f.Close())
+	})
+}
+
+// Render writes the page of the last command in `path`, where `path` holds the
+// commands from the root as `xli.Walk` gives.
+func Render(w io.Writer, path []*xli.Command) error {
+	c := path[len(path)-1]
+	d := xli.NewHelpData(c)
+
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "# %s\n", strings.Join(d.Path, " "))
+	if c.Brief != "" {
+		fmt.Fprintf(b, "\n%s\n", c.Brief)
+	}
+	fmt.Fprintf(b, "\n```\n%s\n```\n", d.Usage)
+	if c.Synop != "" {
+		fmt.Fprintf(b, "\n%s\n", strings.TrimSpace(c.Synop))
+	}
+
+	if len(d.Args) > 0 {
+		b.WriteString("\n## Arguments\n\n")
+		b.WriteString("| Name | Optional | Variadic | Default | Description |\n")
+		b.WriteString("|------|----------|----------|---------|-------------|\n")
+		for _, v := range c.Tree() {
+			for _, a := range v.Args {
+				info := a.Info()
+				fmt.Fprintf(b, "| %s | %s | %s | %s | %s |\n",
+					code(info.Name),
+					mark(a.IsOptional()),
+					mark(a.IsMany()),
+					codeIf(info.HasDefault, info.Default),
+					cell(info.Brief),
+				)
+			}
+		}
+	}
+
+	if len(d.Flags) > 0 {
+		b.WriteString("\n## Options\n\n")
+		b.WriteString("| Name | Alias | Type | Default | Required | Category | Description |\n")
+		b.WriteString("|------|-------|------|---------|----------|----------|-------------|\n")
+		for _, g := range d.Flags {
+			for _, f := range g.Flags {
+				alias := ""
+				if f.Alias != 0 {
+					alias = code("-" + string(f.Alias))
+				}
+				fmt.Fprintf(b, "| %s | %s | %s | %s | %s | %s | %s |\n",
+					code("--"+f.Name),
+					alias,
+					codeIf(f.Type != "", f.Type),
+					codeIf(f.HasDefault, f.Default),
+					mark(f.Required),
+					cell(f.Category),
+					cell(f.Brief),
+				)
+			}
+		}
+	}
+
+	if len(d.Commands) > 0 {
+		b.WriteString("\n## Commands\n")
+		for _, g := range d.Commands {
+			if g.Category != "" {
+				fmt.Fprintf(b, "\n### %s\n", g.Category)
+			}
+			b.WriteString("\n| Command | Description |\n")
+			b.WriteString("|---------|-------------|\n")
+			for _, v := range g.Commands {
+				p := append(path[:len(path):len(path)], v)
+				fmt.Fprintf(b, "| [%s](%s) | %s |\n", cell(v.String()), PageName(p), cell(v.Brief))
+			}
+		}
+	}
+
+	if len(d.Examples) > 0 {
+		b.WriteString("\n## Examples\n")
+		for _, v := range d.Examples {
+			b.WriteString("\n")
+			if v.Brief != "" {
+				fmt.Fprintf(b, "%s\n\n", v.Brief)
+			}
+			fmt.Fprintf(b, "```sh\n%s\n```\n", v.Cmdline)
+		}
+	}
+
+	if len(path) > 1 {
+		b.WriteString("\n## See also\n\n")
+		for i := range path[:len(path)-1] {
+			p := path[:i+1]
+			v := p[len(p)-1]
+			names := d.Path[:i+1]
+			if v.Brief == "" {
+				fmt.Fprintf(b, "- [%s](%s)\n", strings.Join(names, " "), PageName(p))
+			} else {
+				fmt.Fprintf(b, "- [%s](%s) - %s\n", strings.Join(names, " "), PageName(p), v.Brief)
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func isHidden(path []*xli.Command) bool {
+	for _, c := range path {
+		if c.Hidden {
+			return true
+		}
+	}
+	return false
+}
+
+// cell escapes `v` to be put in a table cell.
+func cell(v string) string {
+	v = strings.ReplaceAll(v, "|", `\|`)
+	v = strings.ReplaceAll(v, "\n", " ")
+	return v
+}
+
+func code(v string) string {
+	return "`" + cell(v) + "`"
+}
+
+func codeIf(ok bool, v string) string {
+	if !ok {
+		return ""
+	}
+	return code(v)
+}
+
+func mark(v bool) string {
+	if v {
+		return "yes"
+	}
+	return ""
+}
@@ -0,0 +1,144 @@
+package markdown_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lesomnus/xli"
+	"github.com/lesomnus/xli/arg"
+	"github.com/lesomnus/xli/flg"
+	"github.com/lesomnus/xli/internal/x"
+	"github.com/lesomnus/xli/markdown"
+)
+
+func newCmd() *xli.Command {
+	port := 8080
+	return &xli.Command{
+		Name:  "mytool",
+		Brief: "does things",
+		Commands: xli.Commands{
+			&xli.Command{
+				Name:  "server",
+				Brief: "manage servers",
+				Commands: xli.Commands{
+					&xli.Command{
+						Name:  "start",
+						Brief: "start a server",
+						Synop: "Starts servers in the foreground.",
+						Flags: flg.Flags{
+							&flg.Int{Name: "port", Alias: 'p', Brief: "port to listen", Default: &port},
+							&flg.String{Category: "Auth", Name: "token", Brief: "access token | bearer", Required: true},
+						},
+						Args: arg.Args{
+							&arg.RestStrings{Name: "NAME", Brief: "names of the servers"},
+						},
+						Examples: []xli.Example{
+							{Brief: "Start two servers", Cmdline: "-p 80 a b"},
+						},
+					},
+				}.WithCategory("debug",
+					&xli.Command{Name: "dump", Aliases: []string{"d"}},
+				),
+			},
+			&xli.Command{Name: "secret", Hidden: true},
+		},
+	}
+}
+
+func TestRender(t *testing.T) {
+	render := func(names ...string) string {
+		b := &strings.Builder{}
+		err := xli.Walk(newCmd(), func(path []*xli.Command) error {
+			if markdown.PageName(path) == strings.Join(names, "-")+".md" {
+				return markdown.Render(b, path)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b.String()
+	}
+
+	t.Run("leaf", x.F(func(x x.X) {
+		x.Equal("# mytool server start\n"+
+			"\n"+
+			"start a server\n"+
+			"\n"+
+			"```\n"+
+			"mytool server start [NAME...] [options]\n"+
+			"```\n"+
+			"\n"+
+			"Starts servers in the foreground.\n"+
+			"\n"+
+			"## Arguments\n"+
+			"\n"+
+			"| Name | Optional | Variadic | Default | Description |\n"+
+			"|------|----------|----------|---------|-------------|\n"+
+			"| `NAME` | yes | yes |  | names of the servers |\n"+
+			"\n"+
+			"## Options\n"+
+			"\n"+
+			"| Name | Alias | Type | Default | Required | Category | Description |\n"+
+			"|------|-------|------|---------|----------|----------|-------------|\n"+
+			"| `--port` | `-p` | `int` | `8080` |  |  | port to listen |\n"+
+			"| `--token` |  | `string` |  | yes | Auth | access token \\| bearer |\n"+
+			"\n"+
+			"## Examples\n"+
+			"\n"+
+			"Start two servers\n"+
+			"\n"+
+			"```sh\n"+
+			"mytool server start -p 80 a b\n"+
+			"```\n"+
+			"\n"+
+			"## See also\n"+
+			"\n"+
+			"- [mytool](mytool.md) - does things\n"+
+			"- [mytool server](mytool-server.md) - manage servers\n",
+			render("mytool", "server", "start"))
+	}))
+	t.Run("subcommand index", x.F(func(x x.X) {
+		out := render("mytool", "server")
+		x.Contains(out, "## Commands\n"+
+			"\n"+
+			"| Command | Description |\n"+
+			"|---------|-------------|\n"+
+			"| [start](mytool-server-start.md) | start a server |\n"+
+			"\n"+
+			"### debug\n"+
+			"\n"+
+			"| Command | Description |\n"+
+			"|---------|-------------|\n"+
+			"| [dump,d](mytool-server-dump.md) |  |\n")
+	}))
+	t.Run("hidden commands are not indexed", x.F(func(x x.X) {
+		x.NotContains(render("mytool"), "secret")
+	}))
+}
+
+func TestGenerate(t *testing.T) {
+	x := x.New(t)
+
+	dir := t.TempDir()
+	x.NoError(markdown.Generate(dir, newCmd()))
+
+	es, err := os.ReadDir(dir)
+	x.NoError(err)
+
+	names := []string{}
+	for _, e := range es {
+		names = append(names, e.Name())
+	}
+	x.Equal([]string{"mytool-server-dump.md", "mytool-server-start.md", "mytool-server.md", "mytool.md"}, names)
+
+	// Output is stable across runs.
+	a, err := os.ReadFile(filepath.Join(dir, "mytool-server-start.md"))
+	x.NoError(err)
+	x.NoError(markdown.Generate(dir, newCmd()))
+	b, err := os.ReadFile(filepath.Join(dir, "mytool-server-start.md"))
+	x.NoError(err)
+	x.Equal(string(a), string(b))
+}
@@ -0,0 +1,222 @@
+package xli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/lesomnus/xli/frm"
+	"github.com/lesomnus/xli/tab"
+)
+
+// PluginCategory is the category plugins are listed under in help and
+// completion.
+const PluginCategory = "plugins"
+
+// PluginResolver finds external executables that run as subcommands. `path`
+// holds the names of the commands from the root to the command whose
+// subcommand is looked up.
+type PluginResolver interface {
+	// Lookup returns the executable for the subcommand `name`.
+	Lookup(path []string, name string) (string, bool)
+	// List returns the names of the subcommands it can find.
+	List(path []string) []string
+}
+
+// PathPlugins finds plugins as executables named after the path of the
+// command and the subcommand joined by "-", like "mytool-foo" for "mytool foo"
+// and "mytool-remote-foo" for "mytool remote foo". Only names without "-" are
+// listed, but any name can be run.
+type PathPlugins struct {
+	// Dirs are the directories to search; $PATH is searched if empty.
+	Dirs []string
+}
+
+func (p PathPlugins) dirs() []string {
+	if len(p.Dirs) > 0 {
+		return p.Dirs
+	}
+	return filepath.SplitList(os.Getenv("PATH"))
+}
+
+func (p PathPlugins) Lookup(path []string, name string) (string, bool) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return "", false
+	}
+
+	exe := strings.Join(append(slices.Clone(path), name), "-")
+	for _, d := range p.dirs() {
+		if d == "" {
+			continue
+		}
+		if v, err := exec.LookPath(filepath.Join(d, exe)); err == nil {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func (p PathPlugins) List(path []string) []string {
+	prefix := strings.Join(path, "-") + "-"
+
+	vs := []string{}
+	for _, d := range p.dirs() {
+		if d == "" {
+			continue
+		}
+		es, err := os.ReadDir(d)
+		if err != nil {
+			continue
+		}
+		for _, e := range es {
+			name, ok := strings.CutPrefix(e.Name(), prefix)
+			if !ok || e.IsDir() {
+				continue
+			}
+			name = strings.TrimSuffix(name, filepath.Ext(name))
+			if name == "" || strings.Contains(name, "-") || slices.Contains(vs, name) {
+				continue
+			}
+			if _, err := exec.LookPath(filepath.Join(d, e.Name())); err != nil {
+				continue
+			}
+			vs = append(vs, name)
+		}
+	}
+	slices.Sort(vs)
+	return vs
+}
+
+// ExitError is returned by `Run` when a plugin exits with a non-zero status.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// ExitCode returns the status the program should exit with for the error
+// `Run` returned: 0 for nil, the status of the plugin for an `ExitError`, and
+// 1 otherwise.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	e := &ExitError{}
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return 1
+}
+
+// plugin returns the command that runs the plugin `name` of `c`, or nil if
+// there is none. `path` holds the names of the commands from the root to `c`.
+func (c *Command) plugin(path []string, name string) *Command {
+	if c.Plugins == nil {
+		return nil
+	}
+	exe, ok := c.Plugins.Lookup(path, name)
+	if !ok {
+		return nil
+	}
+
+	return &Command{
+		Category: PluginCategory,
+		Name:     name,
+		Handler: OnRun(func(ctx context.Context, cmd *Command, next Next) error {
+			f, ok := frm.From(ctx).(*frame)
+			if !ok {
+				return next(ctx)
+			}
+			if err := runPlugin(ctx, cmd, exe, f.args); err != nil {
+				return err
+			}
+			return next(ctx)
+		}),
+		exe: exe,
+	}
+}
+
+func runPlugin(ctx context.Context, cmd *Command, exe string, args []string) error {
+	p := exec.CommandContext(ctx, exe, args...)
+	p.Stdin = cmd.ReadCloser
+	p.Stdout = cmd.Writer
+	p.Stderr = cmd.ErrWriter
+	if err := p.Run(); err != nil {
+		e := &exec.ExitError{}
+		if errors.As(err, &e) && e.ExitCode() > 0 {
+			return &ExitError{Code: e.ExitCode()}
+		}
+		return fmt.Errorf("plugin %q: %w", filepath.Base(exe), err)
+	}
+	return nil
+}
+
+// pluginCommands returns the plugins of `c` that are not shadowed by its
+// subcommands, as commands for listing. `c` must be linked to the root.
+func (c *Command) pluginCommands() Commands {
+	if c.Plugins == nil {
+		return nil
+	}
+
+	path := []string{}
+	for _, v := range c.Tree() {
+		path = append(path, v.Name)
+	}
+
+	vs := Commands{}
+	for _, name := range c.Plugins.List(path) {
+		if c.Commands.Get(name) != nil {
+			continue
+		}
+		vs = append(vs, &Command{Category: PluginCategory, Name: name})
+	}
+	return vs
+}
+
+// completePlugin lets the plugin being completed, if any, complete the
+// command line by running it with the completion tag. `args` are the words
+// before the tag and `norm` are them normalized.
+func (c *Command) completePlugin(ctx context.Context, w io.Writer, args []string, norm []string, tag string, curr string, buff string) bool {
+	f_root, err := parseFrameAll(c, norm)
+	if err != nil {
+		return false
+	}
+	f := f_root.Last()
+	if f.c_curr.exe == "" {
+		return false
+	}
+
+	// `norm` differs from `args` only in the last word, which is `curr`.
+	vs := slices.Clone(f.args)
+	if curr != "" {
+		if len(norm) == len(args) {
+			vs = vs[:len(vs)-1]
+		}
+		vs = append(vs, curr)
+	}
+
+	p := exec.CommandContext(ctx, f.c_curr.exe, append(vs, tag, curr, buff)...)
+	p.Stdout = w
+	p.Run()
+	return true
+}
+
+// completePlugins emits the plugins of `c`.
+func completePlugins(t tab.Tab, c *Command) {
+	vs := c.pluginCommands()
+	if len(vs) == 0 {
+		return
+	}
+	t = t.Group(PluginCategory)
+	for _, v := range vs {
+		t.Value(v.Name)
+	}
+}
@@ -0,0 +1,125 @@
+package xli_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/lesomnus/xli"
+	"github.com/lesomnus/xli/internal/x"
+)
+
+func TestPlugins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are shell scripts")
+	}
+
+	dir := t.TempDir()
+	plugin := func(name string, script string) {
+		err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), 0o755)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	plugin("app-echo", `echo "echo:$*"; cat`)
+	plugin("app-fail", `echo oops >&2; exit 3`)
+	plugin("app-ping", `echo plugin-ping`)
+	plugin("app-sub-deep", `echo "deep:$*"`)
+	plugin("app-tab", `echo "tab:$*"`)
+	os.WriteFile(filepath.Join(dir, "app-data"), nil, 0o644)
+
+	newCmd := func() *xli.Command {
+		return &xli.Command{
+			Name:    "app",
+			Plugins: xli.PathPlugins{Dirs: []string{dir}},
+			Commands: xli.Commands{
+				&xli.Command{Name: "ping", Brief: "ping-brief"},
+				&xli.Command{Name: "sub", Plugins: xli.PathPlugins{Dirs: []string{dir}}},
+			},
+		}
+	}
+	run := func(c *xli.Command, stdin string, args ...string) (string, string, error) {
+		o := &strings.Builder{}
+		e := &strings.Builder{}
+		c.ReadCloser = io.NopCloser(strings.NewReader(stdin))
+		c.Writer = o
+		c.ErrWriter = e
+		err := c.Run(context.Background(), args)
+		return o.String(), e.String(), err
+	}
+
+	t.Run("rest of the command line and stdio are forwarded", x.F(func(x x.X) {
+		o, _, err := run(newCmd(), "input\n", "echo", "--foo", "bar", "--", "baz")
+		x.NoError(err)
+		x.Equal("echo:--foo bar -- baz\ninput\n", o)
+	}))
+	t.Run("words for the plugin are not expanded", x.F(func(x x.X) {
+		resp := filepath.Join(t.TempDir(), "resp")
+		x.NoError(os.WriteFile(resp, []byte("echo"), 0o644))
+
+		c := newCmd()
+		c.UserAliases = &aliasStore{vs: []xli.UserAlias{{Name: "lit", Args: []string{"ping"}}}}
+		o, _, err := run(c, "", "@"+resp, "--x", "@"+resp, "@@lit", "lit")
+		x.NoError(err)
+		x.Equal("echo:--x @"+resp+" @@lit lit\n", o)
+	}))
+	t.Run("exit status is returned", x.F(func(x x.X) {
+		_, e, err := run(newCmd(), "", "fail")
+		x.Equal("oops\n", e)
+		x.Equal(3, xli.ExitCode(err))
+
+		v := &xli.ExitError{}
+		x.True(errors.As(err, &v))
+		x.Equal(3, v.Code)
+	}))
+	t.Run("commands take precedence", x.F(func(x x.X) {
+		o, _, err := run(newCmd(), "", "ping")
+		x.NoError(err)
+		x.Equal("", o)
+	}))
+	t.Run("plugin of subcommand", x.F(func(x x.X) {
+		o, _, err := run(newCmd(), "", "sub", "deep", "a")
+		x.NoError(err)
+		x.Equal("deep:a\n", o)
+	}))
+	t.Run("unknown subcommand", x.F(func(x x.X) {
+		_, _, err := run(newCmd(), "", "data")
+		x.True(errors.Is(err, xli.ErrUnknownCmd))
+		x.Equal(1, xli.ExitCode(err))
+		x.Equal(0, xli.ExitCode(nil))
+	}))
+	t.Run("listed in help", x.F(func(x x.X) {
+		o, _, err := run(newCmd(), "", "--help")
+		x.NoError(err)
+		x.Contains(o, "plugins:")
+		x.Contains(o, "echo")
+		x.Contains(o, "fail")
+		x.NotContains(o, "data")
+		x.NotContains(o, "sub-deep")
+	}))
+	t.Run("help data is left to the tree", x.F(func(x x.X) {
+		d := xli.NewHelpData(newCmd())
+		for _, g := range d.Commands {
+			x.NotEqual("plugins", g.Category)
+		}
+	}))
+	t.Run("listed in completion", x.F(func(x x.X) {
+		out := complete(t, newCmd(), "", "")
+		x.Contains(out, "plugins\x1fecho\n")
+		x.Contains(out, "plugins\x1ftab\n")
+		x.NotContains(out, "plugins\x1fping")
+		x.NotContains(out, "data")
+	}))
+	t.Run("completion is delegated", x.F(func(x x.X) {
+		out := complete(t, newCmd(), "--f", "--f", "tab", "a", "--f")
+		x.Equal("tab:a --f $$xli_completion_zsh --f --f\n", out)
+
+		out = complete(t, newCmd(), "", "", "tab", "a")
+		x.Equal("tab:a $$xli_completion_zsh  \n", out)
+	}))
+}
@@ -0,0 +1,241 @@
+package xli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lesomnus/xli/arg"
+	"github.com/lesomnus/xli/flg"
+	"github.com/lesomnus/xli/internal/term"
+	"github.com/lesomnus/xli/lex"
+	"github.com/lesomnus/xli/mode"
+	"github.com/lesomnus/xli/tab"
+)
+
+type PromptMode int
+
+const (
+	// PromptUnset follows the mode of the parent command; it is `PromptNever`
+	// for the root.
+	PromptUnset PromptMode = iota
+	PromptNever
+	// PromptAuto prompts when the input of the command is a terminal.
+	PromptAuto
+	PromptAlways
+)
+
+// promptMode returns the nearest prompt mode set up the frames.
+func (f *frame) promptMode() PromptMode {
+	for ; f != nil; f = f.prev {
+		if f.c_curr != nil && f.c_curr.Prompt != PromptUnset {
+			return f.c_curr.Prompt
+		}
+	}
+	return PromptNever
+}
+
+// parseFrameAllPrompt is `parseFrameAll` that asks for the missing required
+// arguments, if prompting is enabled for their commands, and parses `args`
+// again with the values given.
+func (c *Command) parseFrameAllPrompt(ctx context.Context, args []string) (*frame, error) {
+	for {
+		f_root, err := parseFrameAll(c, args)
+		e := &ArgError{}
+		if err == nil || !errors.As(err, &e) || !errors.Is(err, ErrNeedArgs) {
+			return f_root, err
+		}
+
+		f := f_root.Last()
+		p := c.prompter(f)
+		if p == nil {
+			return f_root, err
+		}
+
+		v, err := p.promptArg(ctx, f.c_curr.Args[len(f.args)], e)
+		if err != nil {
+			return f_root, err
+		}
+		args = slices.Insert(slices.Clone(args), e.Index(), v)
+	}
+}
+
+// prompter asks for values of missing required flags and arguments.
+type prompter struct {
+	r     io.Reader
+	w     io.Writer
+	style Style
+}
+
+func (c *Command) prompter(f *frame) *prompter {
+	r, w := f.io()
+	switch f.promptMode() {
+	case PromptAlways:
+	case PromptAuto:
+		if !term.IsTerminal(r) {
+			return nil
+		}
+	default:
+		return nil
+	}
+	return &prompter{r: r, w: w, style: c.style(w)}
+}
+
+// io returns the nearest input and output set up the frames. The commands
+// inherit them only when they run, which is after the prompts.
+func (f *frame) io() (io.ReadCloser, io.Writer) {
+	var r io.ReadCloser
+	var w io.Writer
+	for ; f != nil; f = f.prev {
+		if f.c_curr == nil {
+			continue
+		}
+		if r == nil {
+			r = f.c_curr.ReadCloser
+		}
+		if w == nil {
+			w = f.c_curr.Writer
+		}
+	}
+	return r, w
+}
+
+// promptArg asks for the value of the missing argument reported by `e` until
+// it is parsed without an error, and returns the word given.
+func (p *prompter) promptArg(ctx context.Context, a arg.Arg, e *ArgError) (string, error) {
+	info := a.Info()
+	return p.ask(info.Name, info.Brief, isSecret(a), argChoices(ctx, info), func(v string) error {
+		if _, ok := lex.Lex(v).(lex.Arg); !ok {
+			return fmt.Errorf("%q cannot be given as an argument", v)
+		}
+		_, err := a.Parse([]string{v})
+		return err
+	}, e)
+}
+
+// promptFlag asks for the value of the missing flag until it is handled
+// without an error.
+func (p *prompter) promptFlag(ctx context.Context, h flg.Flag, e *FlagError) error {
+	info := h.Info()
+	_, err := p.ask("--"+info.Name, info.Brief, isSecret(h), flagChoices(ctx, h), func(v string) error {
+		return h.Handle(ctx, v)
+	}, e)
+	return err
+}
+
+// ask writes a question and reads answers until `parse` accepts one. If the
+// input ends, the error `e` of the missing value is returned.
+func (p *prompter) ask(name string, brief string, secret bool, choices []choice, parse func(v string) error, e error) (string, error) {
+	q := p.style.Flag(name)
+	if brief != "" {
+		q += " (" + brief + ")"
+	}
+
+	for {
+		if len(choices) > 0 {
+			fmt.Fprintf(p.w, "%s:\n", q)
+			for i, c := range choices {
+				if c.desc == "" {
+					fmt.Fprintf(p.w, "  %d) %s\n", i+1, c.value)
+				} else {
+					fmt.Fprintf(p.w, "  %d) %s - %s\n", i+1, c.value, c.desc)
+				}
+			}
+			fmt.Fprintf(p.w, "Choose [1-%d]: ", len(choices))
+		} else {
+			fmt.Fprintf(p.w, "%s: ", q)
+		}
+
+		v, err := p.readLine(secret)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return "", e
+			}
+			return "", err
+		}
+		if i, err := strconv.Atoi(v); err == nil && i > 0 && i <= len(choices) {
+			v = choices[i-1].value
+		}
+		if err := parse(v); err != nil {
+			fmt.Fprintf(p.w, "%s %s\n", p.style.Error("error:"), err.Error())
+			continue
+		}
+		return v, nil
+	}
+}
+
+// readLine reads a line a byte at a time so the input after it is left for
+// the command.
+func (p *prompter) readLine(secret bool) (string, error) {
+	if secret {
+		if restore, ok := term.NoEcho(p.r); ok {
+			defer func() {
+				restore()
+				fmt.Fprintln(p.w)
+			}()
+		}
+	}
+
+	b := []byte{}
+	c := make([]byte, 1)
+	for {
+		n, err := p.r.Read(c)
+		if n > 0 {
+			if c[0] == '\n' {
+				return strings.TrimSuffix(string(b), "\r"), nil
+			}
+			b = append(b, c[0])
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) && len(b) > 0 {
+				return string(b), nil
+			}
+			return "", err
+		}
+	}
+}
+
+// isSecret reports whether the input for `v` should not be echoed. A flag or
+// an argument opts in by implementing `IsSecret() bool`.
+func isSecret(v any) bool {
+	s, ok := v.(interface{ IsSecret() bool })
+	return ok && s.IsSecret()
+}
+
+type choice struct {
+	value string
+	desc  string
+}
+
+// choices collects the candidates a completion handler emits, so they can be
+// offered as a menu.
+type choices struct {
+	vs []choice
+}
+
+func (c *choices) Value(v string)               { c.vs = append(c.vs, choice{value: v}) }
+func (c *choices) ValueD(v string, desc string) { c.vs = append(c.vs, choice{value: v, desc: desc}) }
+func (c *choices) Group(name string) tab.Tab    { return c }
+func (c *choices) Directive(d tab.Directive)    {}
+
+func flagChoices(ctx context.Context, h flg.Flag) []choice {
+	c := &choices{}
+	ctx = tab.Into(mode.Into(ctx, mode.Tab), c)
+	h.Handle(ctx, "")
+	return c.vs
+}
+
+func argChoices(ctx context.Context, info *arg.Info) []choice {
+	if info.Handle == nil {
+		return nil
+	}
+
+	c := &choices{}
+	ctx = tab.Into(mode.Into(ctx, mode.Tab), c)
+	info.Handle(ctx)
+	return c.vs
+}
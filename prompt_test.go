@@ -0,0 +1,204 @@
+package xli_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/lesomnus/xli"
+	"github.com/lesomnus/xli/arg"
+	"github.com/lesomnus/xli/flg"
+	"github.com/lesomnus/xli/internal/x"
+	"github.com/lesomnus/xli/tab"
+)
+
+func TestPrompt(t *testing.T) {
+	run := func(c *xli.Command, input string, args ...string) (string, error) {
+		t.Setenv("NO_COLOR", "1")
+
+		b := &strings.Builder{}
+		c.ReadCloser = io.NopCloser(strings.NewReader(input))
+		c.Writer = b
+		err := c.Run(t.Context(), args)
+		return b.String(), err
+	}
+
+	t.Run("missing argument", x.F(func(x x.X) {
+		var name string
+		c := &xli.Command{
+			Name:   "app",
+			Prompt: xli.PromptAlways,
+			Args: arg.Args{
+				&arg.String{Name: "NAME", Brief: "your name"},
+			},
+			Handler: xli.OnRun(func(ctx context.Context, cmd *xli.Command, next xli.Next) error {
+				name = arg.MustGet[string](cmd, "NAME")
+				return next(ctx)
+			}),
+		}
+		out, err := run(c, "foo\n")
+		x.NoError(err)
+		x.Equal("NAME (your name): ", out)
+		x.Equal("foo", name)
+	}))
+	t.Run("re-prompts on invalid input", x.F(func(x x.X) {
+		var n int
+		c := &xli.Command{
+			Name:   "app",
+			Prompt: xli.PromptAlways,
+			Args: arg.Args{
+				&arg.Int{Name: "N"},
+			},
+			Handler: xli.OnRun(func(ctx context.Context, cmd *xli.Command, next xli.Next) error {
+				n = arg.MustGet[int](cmd, "N")
+				return next(ctx)
+			}),
+		}
+		out, err := run(c, "abc\n-x\n42\n")
+		x.NoError(err)
+		x.Equal(42, n)
+		x.Equal(3, strings.Count(out, "N: "))
+		x.Equal(2, strings.Count(out, "error: "))
+	}))
+	t.Run("missing arguments of a subcommand", x.F(func(x x.X) {
+		var src, dst string
+		c := &xli.Command{
+			Name:   "app",
+			Prompt: xli.PromptAlways,
+			Commands: xli.Commands{
+				&xli.Command{
+					Name: "cp",
+					Args: arg.Args{
+						&arg.String{Name: "SRC"},
+						&arg.String{Name: "DST"},
+					},
+					Handler: xli.OnRun(func(ctx context.Context, cmd *xli.Command, next xli.Next) error {
+						src = arg.MustGet[string](cmd, "SRC")
+						dst = arg.MustGet[string](cmd, "DST")
+						return next(ctx)
+					}),
+				},
+			},
+		}
+		out, err := run(c, "b\n", "cp", "a")
+		x.NoError(err)
+		x.Equal("DST: ", out)
+		x.Equal("a", src)
+		x.Equal("b", dst)
+	}))
+	t.Run("IO of the subcommand", x.F(func(x x.X) {
+		var name string
+		o := &strings.Builder{}
+		c := &xli.Command{
+			Name:   "app",
+			Prompt: xli.PromptAlways,
+			Commands: xli.Commands{
+				&xli.Command{
+					Name:       "greet",
+					ReadCloser: io.NopCloser(strings.NewReader("foo\n")),
+					Writer:     o,
+					Args:       arg.Args{&arg.String{Name: "NAME"}},
+					Handler: xli.OnRun(func(ctx context.Context, cmd *xli.Command, next xli.Next) error {
+						name = arg.MustGet[string](cmd, "NAME")
+						return next(ctx)
+					}),
+				},
+			},
+		}
+		out, err := run(c, "", "greet")
+		x.NoError(err)
+		x.Equal("", out)
+		x.Equal("NAME: ", o.String())
+		x.Equal("foo", name)
+	}))
+	t.Run("missing required flag", x.F(func(x x.X) {
+		var token string
+		c := &xli.Command{
+			Name:   "app",
+			Prompt: xli.PromptAlways,
+			Flags: flg.Flags{
+				&flg.String{Name: "token", Required: true},
+			},
+			Handler: xli.OnRun(func(ctx context.Context, cmd *xli.Command, next xli.Next) error {
+				token = flg.MustGet[string](cmd, "token")
+				return next(ctx)
+			}),
+		}
+		out, err := run(c, "secret\n")
+		x.NoError(err)
+		x.Equal("--token: ", out)
+		x.Equal("secret", token)
+	}))
+	t.Run("choices from the completion handler", x.F(func(x x.X) {
+		var level string
+		c := &xli.Command{
+			Name:   "app",
+			Prompt: xli.PromptAlways,
+			Flags: flg.Flags{
+				&flg.String{Name: "level", Required: true, Handler: flg.OnTab[string](func(ctx context.Context, t tab.Tab) error {
+					t.Value("debug")
+					t.ValueD("info", "the default")
+					return nil
+				})},
+			},
+			Handler: xli.OnRun(func(ctx context.Context, cmd *xli.Command, next xli.Next) error {
+				level = flg.MustGet[string](cmd, "level")
+				return next(ctx)
+			}),
+		}
+		out, err := run(c, "2\n")
+		x.NoError(err)
+		x.Equal("--level:\n  1) debug\n  2) info - the default\nChoose [1-2]: ", out)
+		x.Equal("info", level)
+	}))
+	t.Run("end of input", x.F(func(x x.X) {
+		c := &xli.Command{
+			Name:   "app",
+			Prompt: xli.PromptAlways,
+			Args:   arg.Args{&arg.String{Name: "NAME"}},
+		}
+		_, err := run(c, "")
+		x.True(errors.Is(err, xli.ErrNeedArgs))
+
+		c = &xli.Command{
+			Name:   "app",
+			Prompt: xli.PromptAlways,
+			Flags:  flg.Flags{&flg.String{Name: "token", Required: true}},
+		}
+		_, err = run(c, "")
+		x.True(errors.Is(err, xli.ErrFlagRequired))
+	}))
+	t.Run("not prompted unless the input is a terminal", x.F(func(x x.X) {
+		c := &xli.Command{
+			Name:   "app",
+			Prompt: xli.PromptAuto,
+			Args:   arg.Args{&arg.String{Name: "NAME"}},
+		}
+		out, err := run(c, "foo\n")
+		x.True(errors.Is(err, xli.ErrNeedArgs))
+		x.Equal("", out)
+	}))
+	t.Run("mode is inherited", x.F(func(x x.X) {
+		c := &xli.Command{
+			Name:   "app",
+			Prompt: xli.PromptAlways,
+			Commands: xli.Commands{
+				&xli.Command{
+					Name: "a",
+					Args: arg.Args{&arg.String{Name: "NAME"}},
+				},
+				&xli.Command{
+					Name:   "b",
+					Prompt: xli.PromptNever,
+					Args:   arg.Args{&arg.String{Name: "NAME"}},
+				},
+			},
+		}
+		_, err := run(c, "foo\n", "a")
+		x.NoError(err)
+		_, err = run(c, "foo\n", "b")
+		x.True(errors.Is(err, xli.ErrNeedArgs))
+	}))
+}
@@ -0,0 +1,133 @@
+package xli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lesomnus/xli/lex"
+	"github.com/lesomnus/xli/tab"
+)
+
+// MaxResponseFileDepth is the number of nested response files that are
+// expanded before giving up, which also stops files that include each other.
+const MaxResponseFileDepth = 8
+
+var ErrResponseFile = errors.New("invalid response file")
+
+// ExpandResponseFiles replaces each word "@FILE" in `args` with the words in
+// FILE, split by `lex.Split`: words are separated by whitespace, including
+// newlines, and quoted as in a POSIX shell, and a "#" at the start of a word
+// comments out the rest of the line. Words read from a file are expanded too,
+// up to `MaxResponseFileDepth` files deep.
+//
+// "@@..." is the word "@..." itself, and words after "--" are left as they are.
+// Since it does not know the flags, a word given as the value of a flag is
+// expanded too. `Run` leaves such words to the flag as typed, "@@..."
+// included, since a flag such as `flg.Secret` reads "@" words itself.
+func ExpandResponseFiles(args []string) ([]string, error) {
+	vs, _, err := expandResponseFiles(args, 0, nil)
+	return vs, err
+}
+
+// expandResponseFiles is `ExpandResponseFiles` that leaves the values of the
+// flags of `c` and its subcommands to the flags.
+func (c *Command) expandResponseFiles(args []string) ([]string, error) {
+	vs, _, err := expandResponseFiles(args, 0, newFlagScan(c))
+	return vs, err
+}
+
+// expandResponseFiles also reports whether "--" was seen, so the words after it
+// in the enclosing file or command line are left as they are.
+func expandResponseFiles(args []string, depth int, s *flagScan) ([]string, bool, error) {
+	vs := make([]string, 0, len(args))
+	for i, v := range args {
+		if v == "--" {
+			return append(vs, args[i:]...), true, nil
+		}
+		if _, ok := s.next(v); ok {
+			// Such as "@FILE" or "@fd:N" for a `flg.Secret`, or a word given
+			// to a plugin.
+			vs = append(vs, v)
+			continue
+		}
+
+		p, ok := strings.CutPrefix(v, "@")
+		if !ok || p == "" {
+			vs = append(vs, v)
+			continue
+		}
+		if strings.HasPrefix(p, "@") {
+			vs = append(vs, p)
+			continue
+		}
+
+		if depth == MaxResponseFileDepth {
+			return nil, false, fmt.Errorf("%w: %s: nested more than %d files deep", ErrResponseFile, p, MaxResponseFileDepth)
+		}
+
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return nil, false, fmt.Errorf("%w: %w", ErrResponseFile, err)
+		}
+		words, err := lex.Split(string(b))
+		if err != nil {
+			return nil, false, fmt.Errorf("%w: %s: %w", ErrResponseFile, p, err)
+		}
+		words, end, err := expandResponseFiles(words, depth+1, s)
+		if err != nil {
+			return nil, false, err
+		}
+
+		vs = append(vs, words...)
+		if end {
+			return append(vs, args[i+1:]...), true, nil
+		}
+	}
+	return vs, false, nil
+}
+
+// completeResponseFile emits the paths that complete `curr`, the word
+// "@PATH" being typed.
+func completeResponseFile(t tab.Tab, curr string) {
+	p := strings.TrimPrefix(curr, "@")
+	dir, base := filepath.Split(p)
+
+	d := dir
+	if d == "" {
+		d = "."
+	}
+	// The shell cannot complete the file names itself after the "@".
+	t.Directive(tab.NoFiles)
+	es, err := os.ReadDir(d)
+	if err != nil {
+		return
+	}
+
+	vs := []string{}
+	only_dirs := true
+	for _, e := range es {
+		name := e.Name()
+		if !strings.HasPrefix(name, base) {
+			continue
+		}
+		if strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".") {
+			continue
+		}
+		if e.IsDir() {
+			name += "/"
+		} else {
+			only_dirs = false
+		}
+		vs = append(vs, "@"+dir+name)
+	}
+	if len(vs) > 0 && only_dirs {
+		// A directory is to be continued with a file in it.
+		t.Directive(tab.NoSpace)
+	}
+	for _, v := range vs {
+		t.Value(v)
+	}
+}
@@ -0,0 +1,175 @@
+package xli_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lesomnus/xli"
+	"github.com/lesomnus/xli/arg"
+	"github.com/lesomnus/xli/flg"
+	"github.com/lesomnus/xli/internal/x"
+	"github.com/lesomnus/xli/tab"
+)
+
+func TestExpandResponseFiles(t *testing.T) {
+	write := func(t *testing.T, name string, content string) string {
+		p := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	t.Run("words without @ are left as they are", x.F(func(x x.X) {
+		vs, err := xli.ExpandResponseFiles([]string{"a", "--b=@c", "@"})
+		x.NoError(err)
+		x.Equal([]string{"a", "--b=@c", "@"}, vs)
+	}))
+	t.Run("quoting", x.F(func(x x.X) {
+		p := write(t, "args", ""+
+			"# comment\n"+
+			"--name 'John Doe'  \"say \\\"hi\\\"\"\n"+
+			"a\\ b c#d '' # trailing comment\n"+
+			"\t'it'\"'\"s\n")
+		vs, err := xli.ExpandResponseFiles([]string{"x", "@" + p, "y"})
+		x.NoError(err)
+		x.Equal([]string{"x", "--name", "John Doe", `say "hi"`, "a b", "c#d", "", "it's", "y"}, vs)
+	}))
+	t.Run("unterminated quote", x.F(func(x x.X) {
+		p := write(t, "args", "'oops")
+		_, err := xli.ExpandResponseFiles([]string{"@" + p})
+		x.True(errors.Is(err, xli.ErrResponseFile))
+		x.ErrorContains(err, "unterminated single quote")
+	}))
+	t.Run("nested", x.F(func(x x.X) {
+		inner := write(t, "inner", "b c")
+		outer := write(t, "outer", "a @"+inner+" d")
+		vs, err := xli.ExpandResponseFiles([]string{"@" + outer})
+		x.NoError(err)
+		x.Equal([]string{"a", "b", "c", "d"}, vs)
+	}))
+	t.Run("recursion is limited", x.F(func(x x.X) {
+		p := filepath.Join(t.TempDir(), "self")
+		x.NoError(os.WriteFile(p, []byte("@"+p), 0o644))
+		_, err := xli.ExpandResponseFiles([]string{"@" + p})
+		x.True(errors.Is(err, xli.ErrResponseFile))
+		x.ErrorContains(err, "nested more than")
+	}))
+	t.Run("escaped @", x.F(func(x x.X) {
+		vs, err := xli.ExpandResponseFiles([]string{"@@foo"})
+		x.NoError(err)
+		x.Equal([]string{"@foo"}, vs)
+	}))
+	t.Run("not expanded after --", x.F(func(x x.X) {
+		p := write(t, "args", "a -- @b")
+		vs, err := xli.ExpandResponseFiles([]string{"@" + p, "@c", "--", "@d"})
+		x.NoError(err)
+		x.Equal([]string{"a", "--", "@b", "@c", "--", "@d"}, vs)
+	}))
+	t.Run("missing file", x.F(func(x x.X) {
+		_, err := xli.ExpandResponseFiles([]string{"@" + filepath.Join(t.TempDir(), "nope")})
+		x.True(errors.Is(err, xli.ErrResponseFile))
+		x.True(errors.Is(err, os.ErrNotExist))
+	}))
+}
+
+func TestResponseFilesRun(t *testing.T) {
+	newCmd := func(names *[]string) *xli.Command {
+		return &xli.Command{
+			Name:  "app",
+			Flags: flg.Flags{&flg.Int{Name: "port"}},
+			Args:  arg.Args{&arg.RestStrings{Name: "NAMES"}},
+			Handler: xli.OnRun(func(ctx context.Context, cmd *xli.Command, next xli.Next) error {
+				*names = arg.MustGet[[]string](cmd, "NAMES")
+				return next(ctx)
+			}),
+		}
+	}
+
+	dir := t.TempDir()
+	p := filepath.Join(dir, "args")
+	if err := os.WriteFile(p, []byte("--port 80\nfoo bar"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("expanded", x.F(func(x x.X) {
+		names := []string{}
+		c := newCmd(&names)
+		x.NoError(c.Run(t.Context(), []string{"@" + p, "baz"}))
+		x.Equal([]string{"foo", "bar", "baz"}, names)
+		x.Equal(80, flg.MustGet[int](c, "port"))
+	}))
+	t.Run("opt-out", x.F(func(x x.X) {
+		names := []string{}
+		c := newCmd(&names)
+		c.NoResponseFiles = true
+		x.NoError(c.Run(t.Context(), []string{"@" + p}))
+		x.Equal([]string{"@" + p}, names)
+	}))
+	t.Run("flag values are left to the flag", x.F(func(x x.X) {
+		pw := filepath.Join(dir, "pw")
+		x.NoError(os.WriteFile(pw, []byte("hunter2 secretpart\n"), 0o600))
+
+		var password flg.SecretBytes
+		var name string
+		var names []string
+		c := &xli.Command{
+			Name: "app",
+			Commands: xli.Commands{
+				&xli.Command{
+					Name:  "login",
+					Flags: flg.Flags{&flg.Secret{Name: "password"}, &flg.Int{Name: "port"}, &flg.String{Name: "name"}},
+					Args:  arg.Args{&arg.RestStrings{Name: "NAMES"}},
+					Handler: xli.OnRun(func(ctx context.Context, cmd *xli.Command, next xli.Next) error {
+						password, _ = flg.Get[flg.SecretBytes](cmd, "password")
+						name, _ = flg.Get[string](cmd, "name")
+						names, _ = arg.Get[[]string](cmd, "NAMES")
+						return next(ctx)
+					}),
+				},
+			},
+		}
+
+		x.NoError(c.Run(t.Context(), []string{"login", "--password", "@" + pw, "x"}))
+		x.Equal("hunter2 secretpart", string(password))
+		x.Equal([]string{"x"}, names)
+
+		// Escaped once, by the flag.
+		x.NoError(c.Run(t.Context(), []string{"login", "--password", "@@lit"}))
+		x.Equal("@lit", string(password))
+
+		// Other flags take it as typed.
+		x.NoError(c.Run(t.Context(), []string{"login", "--name", "@@lit"}))
+		x.Equal("@@lit", name)
+
+		// The flag is known from a response file too.
+		resp := filepath.Join(dir, "login")
+		x.NoError(os.WriteFile(resp, []byte("login --password"), 0o644))
+		x.NoError(c.Run(t.Context(), []string{"@" + resp, "@" + pw, "@" + p}))
+		x.Equal("hunter2 secretpart", string(password))
+		x.Equal([]string{"foo", "bar"}, names)
+	}))
+	t.Run("completes paths", x.F(func(x x.X) {
+		x.NoError(os.Mkdir(filepath.Join(dir, "sub"), 0o755))
+		x.NoError(os.WriteFile(filepath.Join(dir, "argv"), nil, 0o644))
+		x.NoError(os.WriteFile(filepath.Join(dir, ".hidden"), nil, 0o644))
+
+		names := []string{}
+		out := complete(t, newCmd(&names), "@"+dir+"/", "@"+dir+"/")
+		x.Contains(out, "\x1f@"+p+"\n")
+		x.Contains(out, "\x1f@"+filepath.Join(dir, "argv")+"\n")
+		x.Contains(out, "\x1f@"+filepath.Join(dir, "sub")+"/\n")
+		x.NotContains(out, ".hidden")
+
+		out = complete(t, newCmd(&names), "@"+dir+"/arg", "@"+dir+"/arg")
+		x.NotContains(out, "sub")
+		x.Equal(tab.NoFiles, tab.ParseZshDirective(out))
+
+		// A directory is to be continued.
+		out = complete(t, newCmd(&names), "@"+dir+"/su", "@"+dir+"/su")
+		x.Equal(tab.NoFiles|tab.NoSpace, tab.ParseZshDirective(out))
+	}))
+}
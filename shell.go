@@ -0,0 +1,440 @@
+package xli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/lesomnus/xli/flg"
+	"github.com/lesomnus/xli/internal/term"
+	"github.com/lesomnus/xli/lex"
+	"github.com/lesomnus/xli/mode"
+	"github.com/lesomnus/xli/tab"
+)
+
+// MaxShellHistory is the number of lines the shell keeps in its history.
+const MaxShellHistory = 1000
+
+type shellCtxKey struct{}
+
+// NewCmdShell returns a "shell" command that reads command lines from its
+// input and runs each against the root, until "exit" or the end of the input.
+// Words are quoted as in a POSIX shell; see `lex.Split`. Errors are printed
+// and the shell goes on. When the input is a terminal, lines can be edited,
+// the history is browsed with the arrow keys, and Tab completes the word
+// before the cursor.
+func NewCmdShell() *Command {
+	return &Command{
+		Name:  "shell",
+		Brief: "Start an interactive shell",
+		Synop: `The history is kept in the user cache directory unless --history is given; an empty --history keeps it in memory only.`,
+		Flags: flg.Flags{
+			&flg.String{Name: "history", Brief: "file to keep the history in"},
+		},
+		Handler: OnRun(func(ctx context.Context, cmd *Command, next Next) error {
+			if ctx.Value(shellCtxKey{}) != nil {
+				return errors.New("already in a shell")
+			}
+			ctx = context.WithValue(ctx, shellCtxKey{}, true)
+			// Each line is run as a command line of its own.
+			ctx = mode.Into(ctx, mode.Unspecified)
+
+			root := cmd.Root()
+			s := &shell{
+				root:   root,
+				cmd:    cmd,
+				prompt: root.Name + "> ",
+			}
+			if v, ok := flg.Get[string](cmd, "history"); ok {
+				s.history_path = v
+			} else if d, err := os.UserCacheDir(); err == nil {
+				s.history_path = filepath.Join(d, root.Name, "history")
+			}
+			s.loadHistory()
+
+			if err := s.run(ctx); err != nil {
+				return err
+			}
+			return next(ctx)
+		}),
+	}
+}
+
+type shell struct {
+	root *Command
+	cmd  *Command
+
+	prompt       string
+	history      []string
+	history_path string
+}
+
+func (s *shell) run(ctx context.Context) error {
+	for {
+		line, err := s.readLine(ctx)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		args, err := lex.Split(line)
+		if err != nil {
+			s.cmd.PrintError(err)
+			continue
+		}
+		if len(args) == 0 {
+			continue
+		}
+		s.addHistory(s.redact(line, args))
+
+		if len(args) == 1 && (args[0] == "exit" || args[0] == "quit") && s.root.Commands.Get(args[0]) == nil {
+			return nil
+		}
+		err = s.root.Invoke(ctx, args, Invocation{
+			ReadCloser: s.cmd.ReadCloser,
+			Writer:     s.cmd.Writer,
+			ErrWriter:  s.cmd.ErrWriter,
+		})
+		if err != nil {
+			s.cmd.PrintError(err)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// readLine reads a line with the editor if the input is a terminal, or as is
+// otherwise. The prompt is written only to a terminal.
+func (s *shell) readLine(ctx context.Context) (string, error) {
+	if !term.IsTerminal(s.cmd.ReadCloser) {
+		return s.readRawLine()
+	}
+
+	restore, ok := term.Raw(s.cmd.ReadCloser)
+	if !ok {
+		fmt.Fprint(s.cmd.Writer, s.prompt)
+		return s.readRawLine()
+	}
+	defer restore()
+
+	e := &editor{s: s, hist_at: len(s.history)}
+	return e.read(ctx)
+}
+
+// readRawLine reads a line a byte at a time so the input after it is left
+// for the commands.
+func (s *shell) readRawLine() (string, error) {
+	b := []byte{}
+	c := make([]byte, 1)
+	for {
+		n, err := s.cmd.ReadCloser.Read(c)
+		if n > 0 {
+			if c[0] == '\n' {
+				return strings.TrimSuffix(string(b), "\r"), nil
+			}
+			b = append(b, c[0])
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) && len(b) > 0 {
+				return string(b), nil
+			}
+			return "", err
+		}
+	}
+}
+
+func (s *shell) loadHistory() {
+	if s.history_path == "" {
+		return
+	}
+	b, err := os.ReadFile(s.history_path)
+	if err != nil {
+		return
+	}
+
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		if line := sc.Text(); line != "" {
+			s.history = append(s.history, line)
+		}
+	}
+	if n := len(s.history); n > MaxShellHistory {
+		s.history = s.history[n-MaxShellHistory:]
+	}
+}
+
+// addHistory appends `line` to the history unless it repeats the last one.
+// The history file is best effort; the shell works without it.
+func (s *shell) addHistory(line string) {
+	if n := len(s.history); n > 0 && s.history[n-1] == line {
+		return
+	}
+	s.history = append(s.history, line)
+	if n := len(s.history); n > MaxShellHistory {
+		s.history = s.history[n-MaxShellHistory:]
+	}
+
+	if s.history_path == "" {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(s.history_path), 0o700); err != nil {
+		return
+	}
+	f, err := os.OpenFile(s.history_path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fmt.Fprintln(f, line)
+}
+
+// redact returns `line` with the values given to the secret flags in `args`,
+// the words of `line`, redacted.
+func (s *shell) redact(line string, args []string) string {
+	vs, ok := redactSecrets(s.root, args)
+	if !ok {
+		return line
+	}
+	return lex.Join(vs)
+}
+
+// complete returns the candidates for the command line `args`, in which
+// `curr` is the word being typed, and the directives on how to complete them
+// by running the completion of the root.
+func (s *shell) complete(ctx context.Context, args []string, curr string) ([]choice, tab.Directive) {
+	b := &bytes.Buffer{}
+	args = append(slices.Clone(args), completion_tag_prefix+"zsh", curr, curr)
+	if err := s.root.Invoke(ctx, args, Invocation{Writer: b, ErrWriter: io.Discard}); err != nil {
+		return nil, 0
+	}
+
+	vs := []choice{}
+	for _, c := range tab.ParseZsh(b.String()) {
+		vs = append(vs, choice{value: c.Value, desc: c.Desc})
+	}
+	return vs, tab.ParseZshDirective(b.String())
+}
+
+// editor edits a line on a terminal in raw mode.
+type editor struct {
+	s *shell
+
+	line []rune
+	pos  int
+
+	// hist_at is the index of the history entry shown; it is the length of
+	// the history while editing a new line, which is kept in `draft`.
+	hist_at int
+	draft   []rune
+}
+
+func (e *editor) read(ctx context.Context) (string, error) {
+	w := e.s.cmd.Writer
+	e.redraw()
+	for {
+		r, err := e.readRune()
+		if err != nil {
+			return "", err
+		}
+
+		switch r {
+		case '\r', '\n':
+			fmt.Fprint(w, "\r\n")
+			return string(e.line), nil
+
+		case 3: // Ctrl-C
+			fmt.Fprint(w, "^C\r\n")
+			e.line = e.line[:0]
+			e.pos = 0
+
+		case 4: // Ctrl-D
+			if len(e.line) == 0 {
+				fmt.Fprint(w, "\r\n")
+				return "", io.EOF
+			}
+			e.delete(e.pos)
+
+		case 127, 8: // Backspace
+			if e.pos > 0 {
+				e.pos--
+				e.delete(e.pos)
+			}
+
+		case 1: // Ctrl-A
+			e.pos = 0
+		case 5: // Ctrl-E
+			e.pos = len(e.line)
+		case 21: // Ctrl-U
+			e.line = slices.Delete(e.line, 0, e.pos)
+			e.pos = 0
+		case '\t':
+			e.complete(ctx)
+
+		case 27: // Escape sequence
+			e.escape()
+
+		default:
+			if unicode.IsPrint(r) {
+				e.line = slices.Insert(e.line, e.pos, r)
+				e.pos++
+			}
+		}
+		e.redraw()
+	}
+}
+
+func (e *editor) readRune() (rune, error) {
+	b := []byte{}
+	c := make([]byte, 1)
+	for {
+		if _, err := io.ReadFull(e.s.cmd.ReadCloser, c); err != nil {
+			return 0, err
+		}
+		b = append(b, c[0])
+		if utf8.FullRune(b) {
+			r, _ := utf8.DecodeRune(b)
+			return r, nil
+		}
+	}
+}
+
+// escape handles the keys sent as "ESC [ ...", such as the arrow keys.
+func (e *editor) escape() {
+	if r, err := e.readRune(); err != nil || r != '[' {
+		return
+	}
+	r, err := e.readRune()
+	if err != nil {
+		return
+	}
+
+	switch r {
+	case 'A':
+		e.browse(-1)
+	case 'B':
+		e.browse(1)
+	case 'C':
+		e.pos = min(e.pos+1, len(e.line))
+	case 'D':
+		e.pos = max(e.pos-1, 0)
+	case 'H':
+		e.pos = 0
+	case 'F':
+		e.pos = len(e.line)
+	case '3':
+		if r, err := e.readRune(); err == nil && r == '~' {
+			e.delete(e.pos)
+		}
+	}
+}
+
+func (e *editor) delete(i int) {
+	if i < len(e.line) {
+		e.line = slices.Delete(e.line, i, i+1)
+	}
+}
+
+// browse shows the history entry `d` entries away from the one shown.
+func (e *editor) browse(d int) {
+	i := e.hist_at + d
+	if i < 0 || i > len(e.s.history) {
+		return
+	}
+	if e.hist_at == len(e.s.history) {
+		e.draft = slices.Clone(e.line)
+	}
+
+	e.hist_at = i
+	if i == len(e.s.history) {
+		e.line = slices.Clone(e.draft)
+	} else {
+		e.line = []rune(e.s.history[i])
+	}
+	e.pos = len(e.line)
+}
+
+// complete completes the word before the cursor. A single candidate replaces
+// the word; otherwise their common prefix does, or they are listed if there
+// is none to add.
+func (e *editor) complete(ctx context.Context) {
+	left := string(e.line[:e.pos])
+	words, cur, err := lex.SplitCursor(left, len(left))
+	if err != nil {
+		return
+	}
+
+	// The word being typed is replaced from its start, quotes included.
+	args := words[:cur.Index]
+	curr := cur.Prefix
+	if cur.Start < len(left) {
+		args = append(args, curr)
+	}
+	start := utf8.RuneCountInString(left[:cur.Start])
+
+	// The candidates are matched against the word by the completion itself.
+	cs, d := e.s.complete(ctx, args, curr)
+	switch len(cs) {
+	case 0:
+		return
+	case 1:
+		v := lex.Quote(cs[0].value)
+		if !d.Is(tab.NoSpace) && !strings.HasSuffix(v, "/") && !strings.HasSuffix(v, "=") {
+			v += " "
+		}
+		e.replace(start, v)
+		return
+	}
+
+	p := cs[0].value
+	for _, c := range cs[1:] {
+		for !strings.HasPrefix(c.value, p) {
+			// Rune by rune, so a multi-byte rune is not cut in half.
+			_, n := utf8.DecodeLastRuneInString(p)
+			p = p[:len(p)-n]
+		}
+	}
+	if len(p) > len(curr) {
+		e.replace(start, lex.Quote(p))
+		return
+	}
+
+	w := e.s.cmd.Writer
+	fmt.Fprint(w, "\r\n")
+	for _, c := range cs {
+		if c.desc == "" {
+			fmt.Fprintf(w, "%s\r\n", c.value)
+		} else {
+			fmt.Fprintf(w, "%s  -- %s\r\n", c.value, c.desc)
+		}
+	}
+}
+
+// replace replaces the runes from `start` to the cursor with `v`.
+func (e *editor) replace(start int, v string) {
+	rs := []rune(v)
+	e.line = slices.Concat(e.line[:start], rs, e.line[e.pos:])
+	e.pos = start + len(rs)
+}
+
+func (e *editor) redraw() {
+	b := &strings.Builder{}
+	b.WriteString("\r\x1b[K")
+	b.WriteString(e.s.prompt)
+	b.WriteString(string(e.line))
+	if n := len(e.line) - e.pos; n > 0 {
+		fmt.Fprintf(b, "\x1b[%dD", n)
+	}
+	io.WriteString(e.s.cmd.Writer, b.String())
+}
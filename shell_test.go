@@ -0,0 +1,115 @@
+package xli_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lesomnus/xli"
+	"github.com/lesomnus/xli/arg"
+	"github.com/lesomnus/xli/flg"
+	"github.com/lesomnus/xli/internal/x"
+)
+
+func TestCmdShell(t *testing.T) {
+	newCmd := func(input string) (*xli.Command, *strings.Builder) {
+		o := &strings.Builder{}
+		return &xli.Command{
+			Name:       "app",
+			ReadCloser: io.NopCloser(strings.NewReader(input)),
+			Writer:     o,
+			ErrWriter:  o,
+			Flags:      flg.Flags{&flg.Switch{Name: "upper"}},
+			Commands: xli.Commands{
+				xli.NewCmdShell(),
+				&xli.Command{
+					Name: "echo",
+					Args: arg.Args{&arg.RestStrings{Name: "WORDS"}},
+					Handler: xli.OnRun(func(ctx context.Context, cmd *xli.Command, next xli.Next) error {
+						vs, _ := arg.Get[[]string](cmd, "WORDS")
+						v := strings.Join(vs, "|")
+						if upper, _ := flg.Get[bool](cmd.Parent(), "upper"); upper {
+							v = strings.ToUpper(v)
+						}
+						cmd.Println(v)
+						return next(ctx)
+					}),
+				},
+			},
+		}, o
+	}
+
+	t.Run("runs each line", x.F(func(x x.X) {
+		history := filepath.Join(t.TempDir(), "history")
+		c, o := newCmd("echo a 'b c'\n\n--upper echo d\necho e")
+		err := c.Run(t.Context(), []string{"shell", "--history", history})
+		x.NoError(err)
+		x.Equal("a|b c\nD\ne\n", o.String())
+
+		b, err := os.ReadFile(history)
+		x.NoError(err)
+		x.Equal("echo a 'b c'\n--upper echo d\necho e\n", string(b))
+	}))
+	t.Run("errors do not stop the shell", x.F(func(x x.X) {
+		c, o := newCmd("foo\necho 'a\necho b\n")
+		err := c.Run(t.Context(), []string{"shell", "--history="})
+		x.NoError(err)
+		x.Contains(o.String(), "error: foo: unknown subcommand")
+		x.Contains(o.String(), "error: unterminated single quote")
+		x.Contains(o.String(), "b\n")
+	}))
+	t.Run("exit", x.F(func(x x.X) {
+		c, o := newCmd("echo a\nexit\necho b\n")
+		err := c.Run(t.Context(), []string{"shell", "--history="})
+		x.NoError(err)
+		x.Equal("a\n", o.String())
+	}))
+	t.Run("shell in shell", x.F(func(x x.X) {
+		c, o := newCmd("shell\n")
+		err := c.Run(t.Context(), []string{"shell", "--history="})
+		x.NoError(err)
+		x.Contains(o.String(), "error: already in a shell")
+	}))
+	t.Run("history is loaded", x.F(func(x x.X) {
+		history := filepath.Join(t.TempDir(), "history")
+		x.NoError(os.WriteFile(history, []byte("echo a\n"), 0o600))
+
+		c, _ := newCmd("echo a\necho b\n")
+		err := c.Run(t.Context(), []string{"shell", "--history", history})
+		x.NoError(err)
+
+		// A line that repeats the last one is not added.
+		b, err := os.ReadFile(history)
+		x.NoError(err)
+		x.Equal("echo a\necho b\n", string(b))
+	}))
+	t.Run("secrets are not kept in the history", x.F(func(x x.X) {
+		history := filepath.Join(t.TempDir(), "history")
+		c := &xli.Command{
+			Name:       "app",
+			ReadCloser: io.NopCloser(strings.NewReader("login --password=hunter2 bob\nlogin -p hunter2\nlogin --password @pw\nlogin --user=bob\n")),
+			Writer:     io.Discard,
+			ErrWriter:  io.Discard,
+			Commands: xli.Commands{
+				xli.NewCmdShell(),
+				&xli.Command{
+					Name: "login",
+					Flags: flg.Flags{
+						&flg.Secret{Name: "password", Alias: 'p'},
+						&flg.String{Name: "user"},
+					},
+					Args: arg.Args{&arg.RestStrings{Name: "NAMES"}},
+				},
+			},
+		}
+		err := c.Run(t.Context(), []string{"shell", "--history", history})
+		x.NoError(err)
+
+		b, err := os.ReadFile(history)
+		x.NoError(err)
+		x.Equal("login '--password=[redacted]' bob\nlogin -p '[redacted]'\nlogin --password @pw\nlogin --user=bob\n", string(b))
+	}))
+}
@@ -0,0 +1,22 @@
+package spec
+
+import (
+	"context"
+
+	"github.com/lesomnus/xli"
+)
+
+// NewCmd returns a hidden "__xli_tree" command that writes the description of
+// the whole tree it is mounted in as JSON.
+func NewCmd() *xli.Command {
+	return &xli.Command{
+		Name:   "__xli_tree",
+		Hidden: true,
+		Handler: xli.OnRun(func(ctx context.Context, cmd *xli.Command, next xli.Next) error {
+			if err := Write(cmd, cmd.Root()); err != nil {
+				return err
+			}
+			return next(ctx)
+		}),
+	}
+}
@@ -0,0 +1,223 @@
+package spec
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/lesomnus/xli"
+)
+
+type ChangeKind int
+
+const (
+	CommandRemoved ChangeKind = iota
+	AliasRemoved
+	FlagRemoved
+	FlagRenamed
+	FlagAliasRemoved
+	FlagRequired
+	FlagTypeChanged
+	ArgRemoved
+	ArgRequired
+	ArgTypeChanged
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case CommandRemoved:
+		return "command removed"
+	case AliasRemoved:
+		return "alias removed"
+	case FlagRemoved:
+		return "flag removed"
+	case FlagRenamed:
+		return "flag renamed"
+	case FlagAliasRemoved:
+		return "flag alias removed"
+	case FlagRequired:
+		return "flag became required"
+	case FlagTypeChanged:
+		return "flag type changed"
+	case ArgRemoved:
+		return "argument removed"
+	case ArgRequired:
+		return "argument became required"
+	case ArgTypeChanged:
+		return "argument type changed"
+	default:
+		return fmt.Sprintf("ChangeKind(%d)", int(k))
+	}
+}
+
+// Change is a change that can break existing invocations.
+type Change struct {
+	Kind ChangeKind
+
+	// Path holds the names of the commands from the root to the changed
+	// command, as they are in the old tree.
+	Path []string
+
+	// Name is the name of the changed alias, flag, or argument. It is empty
+	// when a command is removed.
+	Name string
+
+	// Old and New are the values before and after the change, such as the
+	// types for `FlagTypeChanged` or the names for `FlagRenamed`.
+	Old string
+	New string
+}
+
+func (c Change) String() string {
+	s := fmt.Sprintf("%s: %s", strings.Join(c.Path, " "), c.Kind)
+	switch c.Kind {
+	case CommandRemoved:
+	case FlagRenamed:
+		s += fmt.Sprintf(": --%s to --%s", c.Old, c.New)
+	case FlagAliasRemoved:
+		s += fmt.Sprintf(": -%s of --%s", c.Old, c.Name)
+	case FlagRemoved, FlagRequired, FlagTypeChanged:
+		s += ": --" + c.Name
+	default:
+		s += ": " + c.Name
+	}
+	if c.Kind == FlagTypeChanged || c.Kind == ArgTypeChanged {
+		s += fmt.Sprintf(" from %q to %q", c.Old, c.New)
+	}
+	return s
+}
+
+type Changes []Change
+
+// Err returns an error listing the changes, or nil if there are none.
+func (cs Changes) Err() error {
+	errs := make([]error, len(cs))
+	for i, c := range cs {
+		errs[i] = errors.New(c.String())
+	}
+	return errors.Join(errs...)
+}
+
+// Compare reports the changes from `prev` to `next` that can break
+// invocations that work with `prev`. Additions and relaxations, such as a new
+// optional flag or a required argument that became optional, are not reported.
+func Compare(prev, next *Spec) Changes {
+	cs := Changes{}
+	compareCommand(&cs, []string{prev.Command.Name}, prev.Command, next.Command)
+	return cs
+}
+
+// CompareCommands is `Compare` for the trees rooted at `prev` and `next`.
+func CompareCommands(prev, next *xli.Command) Changes {
+	return Compare(New(prev), New(next))
+}
+
+func compareCommand(cs *Changes, path []string, prev, next *Command) {
+	for _, v := range prev.Aliases {
+		if next.Name != v && !slices.Contains(next.Aliases, v) {
+			*cs = append(*cs, Change{Kind: AliasRemoved, Path: path, Name: v})
+		}
+	}
+
+	compareFlags(cs, path, prev.Flags, next.Flags)
+	compareArgs(cs, path, prev.Args, next.Args)
+
+	for _, v := range prev.Commands {
+		p := append(path[:len(path):len(path)], v.Name)
+		w := next.get(v.Name)
+		if w == nil {
+			*cs = append(*cs, Change{Kind: CommandRemoved, Path: p})
+			continue
+		}
+		compareCommand(cs, p, v, w)
+	}
+}
+
+func compareFlags(cs *Changes, path []string, prev, next []*Flag) {
+	for _, v := range prev {
+		w := getFlag(next, v.Name)
+		if w == nil {
+			if u := getFlagByAlias(next, v.Alias); u != nil && getFlag(prev, u.Name) == nil {
+				*cs = append(*cs, Change{Kind: FlagRenamed, Path: path, Name: v.Name, Old: v.Name, New: u.Name})
+			} else {
+				*cs = append(*cs, Change{Kind: FlagRemoved, Path: path, Name: v.Name})
+			}
+			continue
+		}
+		if v.Alias != "" && v.Alias != w.Alias {
+			*cs = append(*cs, Change{Kind: FlagAliasRemoved, Path: path, Name: v.Name, Old: v.Alias, New: w.Alias})
+		}
+		if !v.Required && w.Required {
+			*cs = append(*cs, Change{Kind: FlagRequired, Path: path, Name: v.Name})
+		}
+		if v.Type != w.Type {
+			*cs = append(*cs, Change{Kind: FlagTypeChanged, Path: path, Name: v.Name, Old: v.Type, New: w.Type})
+		}
+	}
+	for _, w := range next {
+		if !w.Required || getFlag(prev, w.Name) != nil {
+			continue
+		}
+		// A renamed flag is reported as such.
+		if v := getFlagByAlias(prev, w.Alias); v != nil && getFlag(next, v.Name) == nil {
+			continue
+		}
+		*cs = append(*cs, Change{Kind: FlagRequired, Path: path, Name: w.Name})
+	}
+}
+
+// compareArgs compares arguments by position since their names are not part
+// of the command line.
+func compareArgs(cs *Changes, path []string, prev, next []*Arg) {
+	for i, v := range prev {
+		if i >= len(next) {
+			*cs = append(*cs, Change{Kind: ArgRemoved, Path: path, Name: v.Name})
+			continue
+		}
+
+		w := next[i]
+		if v.Optional && !w.Optional {
+			*cs = append(*cs, Change{Kind: ArgRequired, Path: path, Name: w.Name})
+		}
+		if v.Type != w.Type {
+			*cs = append(*cs, Change{Kind: ArgTypeChanged, Path: path, Name: w.Name, Old: v.Type, New: w.Type})
+		}
+	}
+	for _, w := range next[min(len(prev), len(next)):] {
+		if !w.Optional {
+			*cs = append(*cs, Change{Kind: ArgRequired, Path: path, Name: w.Name})
+		}
+	}
+}
+
+// get returns the subcommand with the given name or alias.
+func (c *Command) get(name string) *Command {
+	for _, v := range c.Commands {
+		if v.Name == name || slices.Contains(v.Aliases, name) {
+			return v
+		}
+	}
+	return nil
+}
+
+func getFlag(fs []*Flag, name string) *Flag {
+	for _, f := range fs {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+func getFlagByAlias(fs []*Flag, alias string) *Flag {
+	if alias == "" {
+		return nil
+	}
+	for _, f := range fs {
+		if f.Alias == alias {
+			return f
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package spec_test
+
+import (
+	"testing"
+
+	"github.com/lesomnus/xli"
+	"github.com/lesomnus/xli/arg"
+	"github.com/lesomnus/xli/flg"
+	"github.com/lesomnus/xli/internal/x"
+	"github.com/lesomnus/xli/spec"
+)
+
+func TestCompare(t *testing.T) {
+	t.Run("same tree", x.F(func(x x.X) {
+		cs := spec.CompareCommands(newCmd(), newCmd())
+		x.Len(cs, 0)
+		x.NoError(cs.Err())
+	}))
+	t.Run("additions and relaxations are compatible", x.F(func(x x.X) {
+		prev := &xli.Command{
+			Name: "app",
+			Flags: flg.Flags{
+				&flg.String{Name: "token", Required: true},
+			},
+			Args: arg.Args{
+				&arg.String{Name: "NAME"},
+			},
+		}
+		next := &xli.Command{
+			Name:    "app",
+			Aliases: []string{"a"},
+			Flags: flg.Flags{
+				&flg.String{Name: "token"},
+				&flg.String{Name: "user"},
+			},
+			Args: arg.Args{
+				&arg.String{Name: "NAME", Optional: true},
+				&arg.String{Name: "EXTRA", Optional: true},
+			},
+			Commands: xli.Commands{
+				&xli.Command{Name: "new"},
+			},
+		}
+		x.Len(spec.CompareCommands(prev, next), 0)
+	}))
+	t.Run("commands and aliases", x.F(func(x x.X) {
+		prev := &xli.Command{
+			Name: "app",
+			Commands: xli.Commands{
+				&xli.Command{Name: "serve", Aliases: []string{"s", "run"}},
+				&xli.Command{Name: "ls"},
+				&xli.Command{Name: "rm"},
+			},
+		}
+		next := &xli.Command{
+			Name: "app",
+			Commands: xli.Commands{
+				&xli.Command{Name: "serve", Aliases: []string{"s"}},
+				&xli.Command{Name: "list", Aliases: []string{"ls"}},
+			},
+		}
+		cs := spec.CompareCommands(prev, next)
+		x.Equal(spec.Changes{
+			{Kind: spec.AliasRemoved, Path: []string{"app", "serve"}, Name: "run"},
+			{Kind: spec.CommandRemoved, Path: []string{"app", "rm"}},
+		}, cs)
+		x.ErrorContains(cs.Err(), "app serve: alias removed: run\napp rm: command removed")
+	}))
+	t.Run("flags", x.F(func(x x.X) {
+		prev := &xli.Command{
+			Name: "app",
+			Flags: flg.Flags{
+				&flg.String{Name: "addr", Alias: 'a'},
+				&flg.String{Name: "user"},
+				&flg.String{Name: "token", Alias: 't'},
+				&flg.Int{Name: "port", Alias: 'p'},
+				&flg.String{Name: "dir"},
+			},
+		}
+		next := &xli.Command{
+			Name: "app",
+			Flags: flg.Flags{
+				&flg.String{Name: "address", Alias: 'a'},
+				&flg.String{Name: "token", Required: true},
+				&flg.String{Name: "port", Alias: 'p'},
+				&flg.String{Name: "dir"},
+				&flg.String{Name: "key", Required: true},
+			},
+		}
+		cs := spec.CompareCommands(prev, next)
+		x.Equal(spec.Changes{
+			{Kind: spec.FlagRenamed, Path: []string{"app"}, Name: "addr", Old: "addr", New: "address"},
+			{Kind: spec.FlagRemoved, Path: []string{"app"}, Name: "user"},
+			{Kind: spec.FlagAliasRemoved, Path: []string{"app"}, Name: "token", Old: "t"},
+			{Kind: spec.FlagRequired, Path: []string{"app"}, Name: "token"},
+			{Kind: spec.FlagTypeChanged, Path: []string{"app"}, Name: "port", Old: "int", New: "string"},
+			{Kind: spec.FlagRequired, Path: []string{"app"}, Name: "key"},
+		}, cs)
+		x.ErrorContains(cs.Err(), `app: flag renamed: --addr to --address`)
+		x.ErrorContains(cs.Err(), `app: flag alias removed: -t of --token`)
+		x.ErrorContains(cs.Err(), `app: flag type changed: --port from "int" to "string"`)
+	}))
+	t.Run("args", x.F(func(x x.X) {
+		prev := &xli.Command{
+			Name: "app",
+			Args: arg.Args{
+				&arg.String{Name: "SRC"},
+				&arg.String{Name: "DST", Optional: true},
+				&arg.String{Name: "MODE", Optional: true},
+			},
+		}
+		next := &xli.Command{
+			Name: "app",
+			Args: arg.Args{
+				&arg.Int{Name: "SRC"},
+				&arg.String{Name: "DST"},
+			},
+		}
+		cs := spec.CompareCommands(prev, next)
+		x.Equal(spec.Changes{
+			{Kind: spec.ArgTypeChanged, Path: []string{"app"}, Name: "SRC", Old: "string", New: "int"},
+			{Kind: spec.ArgRequired, Path: []string{"app"}, Name: "DST"},
+			{Kind: spec.ArgRemoved, Path: []string{"app"}, Name: "MODE"},
+		}, cs)
+	}))
+	t.Run("new required arg", x.F(func(x x.X) {
+		prev := &xli.Command{Name: "app"}
+		next := &xli.Command{
+			Name: "app",
+			Args: arg.Args{
+				&arg.String{Name: "NAME"},
+			},
+		}
+		cs := spec.CompareCommands(prev, next)
+		x.Equal(spec.Changes{
+			{Kind: spec.ArgRequired, Path: []string{"app"}, Name: "NAME"},
+		}, cs)
+	}))
+}
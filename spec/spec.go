@@ -0,0 +1,150 @@
+// Package spec describes the shape of a command tree in a serializable form,
+// so other tools can consume a CLI without running it.
+package spec
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/lesomnus/xli"
+	"github.com/lesomnus/xli/flg"
+)
+
+// Version is the version of the format `New` produces. It is bumped only when
+// a change would break existing readers; fields may be added within a version.
+const Version = 1
+
+// Schema is the JSON Schema of the format.
+//
+//go:embed schema.json
+var Schema string
+
+type Spec struct {
+	Version int      `json:"version"`
+	Command *Command `json:"command"`
+}
+
+type Command struct {
+	Name     string   `json:"name"`
+	Aliases  []string `json:"aliases,omitempty"`
+	Category string   `json:"category,omitempty"`
+	Brief    string   `json:"brief,omitempty"`
+	Synop    string   `json:"synop,omitempty"`
+	Hidden   bool     `json:"hidden,omitempty"`
+
+	Flags    []*Flag    `json:"flags,omitempty"`
+	Args     []*Arg     `json:"args,omitempty"`
+	Commands []*Command `json:"commands,omitempty"`
+}
+
+type Flag struct {
+	Name     string `json:"name"`
+	Alias    string `json:"alias,omitempty"`
+	Category string `json:"category,omitempty"`
+	Type     string `json:"type"`
+	Brief    string `json:"brief,omitempty"`
+	Synop    string `json:"synop,omitempty"`
+	Required bool   `json:"required,omitempty"`
+	Secret   bool   `json:"secret,omitempty"`
+
+	// Switch reports whether the flag can be given without a value.
+	Switch bool `json:"switch,omitempty"`
+
+	// Default is nil if the flag has no default.
+	Default *string `json:"default,omitempty"`
+}
+
+type Arg struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Brief    string `json:"brief,omitempty"`
+	Synop    string `json:"synop,omitempty"`
+	Optional bool   `json:"optional,omitempty"`
+	Many     bool   `json:"many,omitempty"`
+
+	// Default is nil if the argument has no default.
+	Default *string `json:"default,omitempty"`
+}
+
+// New describes the tree rooted at `c`, including hidden commands.
+func New(c *xli.Command) *Spec {
+	return &Spec{
+		Version: Version,
+		Command: newCommand(c),
+	}
+}
+
+func newCommand(c *xli.Command) *Command {
+	v := &Command{
+		Name:     c.Name,
+		Aliases:  c.Aliases,
+		Category: c.Category,
+		Brief:    c.Brief,
+		Synop:    c.Synop,
+		Hidden:   c.Hidden,
+	}
+	for _, f := range c.Flags {
+		v.Flags = append(v.Flags, newFlag(f.Info(), f.NoValue()))
+	}
+	for _, a := range c.Args {
+		info := a.Info()
+		u := &Arg{
+			Name:     info.Name,
+			Type:     info.Type,
+			Brief:    info.Brief,
+			Synop:    info.Synop,
+			Optional: a.IsOptional(),
+			Many:     a.IsMany(),
+		}
+		if info.HasDefault {
+			u.Default = &info.Default
+		}
+		v.Args = append(v.Args, u)
+	}
+	for _, w := range c.Commands {
+		v.Commands = append(v.Commands, newCommand(w))
+	}
+	return v
+}
+
+func newFlag(info *flg.Info, no_value bool) *Flag {
+	v := &Flag{
+		Name:     info.Name,
+		Category: info.Category,
+		Type:     info.Type,
+		Brief:    info.Brief,
+		Synop:    info.Synop,
+		Required: info.Required,
+		Secret:   info.Secret,
+		Switch:   no_value,
+	}
+	if info.Alias != 0 {
+		v.Alias = string(info.Alias)
+	}
+	if info.HasDefault {
+		v.Default = &info.Default
+	}
+	return v
+}
+
+// Write writes the description of the tree rooted at `c` to `w` as indented JSON.
+func Write(w io.Writer, c *xli.Command) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "\t")
+	return e.Encode(New(c))
+}
+
+// Read reads a description written by `Write`. It fails if the description is
+// of a version other than `Version`.
+func Read(r io.Reader) (*Spec, error) {
+	s := &Spec{}
+	if err := json.NewDecoder(r).Decode(s); err != nil {
+		return nil, err
+	}
+	if s.Version != Version {
+		return nil, fmt.Errorf("unsupported version %d; expected %d", s.Version, Version)
+	}
+	return s, nil
+}
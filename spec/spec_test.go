@@ -0,0 +1,118 @@
+package spec_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/lesomnus/xli"
+	"github.com/lesomnus/xli/arg"
+	"github.com/lesomnus/xli/flg"
+	"github.com/lesomnus/xli/internal/x"
+	"github.com/lesomnus/xli/spec"
+)
+
+func newCmd() *xli.Command {
+	port := 8080
+	return &xli.Command{
+		Name:    "mytool",
+		Aliases: []string{"mt"},
+		Brief:   "does things",
+		Flags: flg.Flags{
+			&flg.Switch{Name: "verbose", Alias: 'v'},
+		},
+		Commands: xli.Commands{
+			&xli.Command{
+				Name:  "start",
+				Synop: "Starts a server.",
+				Flags: flg.Flags{
+					&flg.Int{Category: "Network", Name: "port", Default: &port},
+					&flg.String{Name: "token", Required: true},
+				},
+				Args: arg.Args{
+					&arg.String{Name: "NAME"},
+					&arg.RestStrings{Name: "OPTS"},
+				},
+			},
+			&xli.Command{Name: "debug", Category: "dev", Hidden: true},
+			spec.NewCmd(),
+		},
+	}
+}
+
+func TestNew(t *testing.T) {
+	x := x.New(t)
+
+	s := spec.New(newCmd())
+	x.Equal(spec.Version, s.Version)
+
+	c := s.Command
+	x.Equal("mytool", c.Name)
+	x.Equal([]string{"mt"}, c.Aliases)
+	x.Equal("does things", c.Brief)
+	x.Equal("v", c.Flags[0].Alias)
+	x.True(c.Flags[0].Switch)
+	x.Len(c.Commands, 3)
+
+	start := c.Commands[0]
+	x.Equal("Starts a server.", start.Synop)
+	x.Equal("Network", start.Flags[0].Category)
+	x.Equal("int", start.Flags[0].Type)
+	x.Equal("8080", *start.Flags[0].Default)
+	x.True(start.Flags[1].Required)
+	x.Nil(start.Flags[1].Default)
+	x.Equal(&spec.Arg{Name: "NAME", Type: "string"}, start.Args[0])
+	x.Equal(&spec.Arg{Name: "OPTS", Type: "string...", Optional: true, Many: true}, start.Args[1])
+
+	debug := c.Commands[1]
+	x.Equal("dev", debug.Category)
+	x.True(debug.Hidden)
+}
+
+func TestReadWrite(t *testing.T) {
+	t.Run("round trip", x.F(func(x x.X) {
+		b := &strings.Builder{}
+		x.NoError(spec.Write(b, newCmd()))
+
+		s, err := spec.Read(strings.NewReader(b.String()))
+		x.NoError(err)
+		x.Equal(spec.New(newCmd()), s)
+	}))
+	t.Run("unknown version", x.F(func(x x.X) {
+		_, err := spec.Read(strings.NewReader(`{"version": 2, "command": {"name": "a"}}`))
+		x.ErrorContains(err, "unsupported version 2")
+	}))
+}
+
+func TestNewCmd(t *testing.T) {
+	t.Run("writes the whole tree", x.F(func(x x.X) {
+		b := &strings.Builder{}
+		c := newCmd()
+		c.Writer = b
+		x.NoError(c.Run(t.Context(), []string{"__xli_tree"}))
+
+		s, err := spec.Read(strings.NewReader(b.String()))
+		x.NoError(err)
+		x.Equal("mytool", s.Command.Name)
+		x.Equal("__xli_tree", s.Command.Commands[2].Name)
+		x.True(s.Command.Commands[2].Hidden)
+	}))
+	t.Run("not listed in help", x.F(func(x x.X) {
+		b := &strings.Builder{}
+		c := newCmd()
+		c.Writer = b
+		x.NoError(c.Run(t.Context(), []string{"--help"}))
+		x.NotContains(b.String(), "__xli_tree")
+	}))
+}
+
+func TestSchema(t *testing.T) {
+	x := x.New(t)
+
+	v := map[string]any{}
+	x.NoError(json.Unmarshal([]byte(spec.Schema), &v))
+
+	props := v["properties"].(map[string]any)
+	version := props["version"].(map[string]any)
+	x.Equal(float64(spec.Version), version["const"])
+}
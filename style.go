@@ -0,0 +1,177 @@
+package xli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lesomnus/xli/flg"
+	"github.com/lesomnus/xli/internal/term"
+	"github.com/lesomnus/xli/tab"
+)
+
+// Palette holds the SGR parameters, such as "1;36" for bold cyan, used to
+// style each element of help and error output. An empty parameter leaves the
+// element unstyled.
+type Palette struct {
+	Heading  string
+	Command  string
+	Flag     string
+	Required string
+	Default  string
+	Error    string
+}
+
+var DefaultPalette = Palette{
+	Heading:  "1",
+	Command:  "36",
+	Flag:     "36",
+	Required: "31",
+	Default:  "2",
+	Error:    "1;31",
+}
+
+// Style applies a palette to text. It leaves text as is when it is not enabled.
+type Style struct {
+	Palette Palette
+	Enabled bool
+}
+
+func (s Style) Heading(v string) string  { return s.apply(s.Palette.Heading, v) }
+func (s Style) Command(v string) string  { return s.apply(s.Palette.Command, v) }
+func (s Style) Flag(v string) string     { return s.apply(s.Palette.Flag, v) }
+func (s Style) Required(v string) string { return s.apply(s.Palette.Required, v) }
+func (s Style) Default(v string) string  { return s.apply(s.Palette.Default, v) }
+func (s Style) Error(v string) string    { return s.apply(s.Palette.Error, v) }
+
+func (s Style) apply(p string, v string) string {
+	if !s.Enabled || p == "" || v == "" {
+		return v
+	}
+	return fmt.Sprintf("\x1b[%sm%s\x1b[0m", p, v)
+}
+
+type ColorMode int
+
+const (
+	// ColorAuto styles output written to a terminal unless `$NO_COLOR` is set;
+	// `$FORCE_COLOR` styles it regardless of the terminal.
+	ColorAuto ColorMode = iota
+	ColorAlways
+	ColorNever
+)
+
+type ColorModeParser struct{}
+
+func (ColorModeParser) Parse(s string) (ColorMode, error) {
+	switch s {
+	case "auto":
+		return ColorAuto, nil
+	case "always":
+		return ColorAlways, nil
+	case "never":
+		return ColorNever, nil
+	default:
+		return ColorAuto, fmt.Errorf(`expected one of "auto", "always", or "never" but %q`, s)
+	}
+}
+
+func (ColorModeParser) ToString(v ColorMode) string {
+	switch v {
+	case ColorAlways:
+		return "always"
+	case ColorNever:
+		return "never"
+	default:
+		return "auto"
+	}
+}
+
+func (ColorModeParser) String() string {
+	return "auto|always|never"
+}
+
+// NewFlagColor returns a "--color" flag that controls styling of the help and
+// error output of the command it is added to and of its descendants.
+func NewFlagColor() flg.Flag {
+	auto := ColorAuto
+	return &flg.Base[ColorMode, ColorModeParser]{
+		Name:    "color",
+		Brief:   "when to use colors",
+		Default: &auto,
+		Handler: flg.OnTab[ColorMode](func(ctx context.Context, t tab.Tab) error {
+			t.Value("auto")
+			t.Value("always")
+			t.Value("never")
+			return nil
+		}),
+	}
+}
+
+// style returns the style for output of `c` to `w`. The palette is the nearest
+// one up the tree, and colors are enabled according to the "--color" flag
+// given to `c` or its ancestors, the environment, and whether `w` is a terminal.
+func (c *Command) style(w io.Writer) Style {
+	s := Style{Palette: DefaultPalette}
+	for p := c; p != nil; p = p.parent {
+		if p.Palette != nil {
+			s.Palette = *p.Palette
+			break
+		}
+	}
+
+	m := ColorAuto
+	flg.Lookup(c, "color", func(v ColorMode) { m = v })
+	switch m {
+	case ColorAlways:
+		s.Enabled = true
+	case ColorNever:
+		s.Enabled = false
+	default:
+		if os.Getenv("NO_COLOR") != "" {
+			s.Enabled = false
+		} else if v := os.Getenv("FORCE_COLOR"); v != "" && v != "0" {
+			s.Enabled = true
+		} else {
+			s.Enabled = term.IsTerminal(w)
+		}
+	}
+	return s
+}
+
+// PrintError writes `err` to the command's `ErrWriter`, or to stderr if it is
+// not set, styled as the help is.
+func (c *Command) PrintError(err error) error {
+	w := c.ErrWriter
+	if w == nil {
+		w = os.Stderr
+	}
+
+	s := c.style(w)
+	_, err = fmt.Fprintf(w, "%s %s\n", s.Error("error:"), err.Error())
+	return err
+}
+
+// textWidth returns the number of columns `s` occupies, not counting ANSI
+// escape sequences.
+func textWidth(s string) int {
+	n := 0
+	for {
+		i := strings.Index(s, "\x1b[")
+		if i < 0 {
+			return n + utf8.RuneCountInString(s)
+		}
+		n += utf8.RuneCountInString(s[:i])
+
+		j := strings.IndexFunc(s[i+2:], func(r rune) bool {
+			return r >= 0x40 && r <= 0x7e
+		})
+		if j < 0 {
+			return n
+		}
+		s = s[i+2+j+1:]
+	}
+}
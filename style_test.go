@@ -0,0 +1,110 @@
+package xli_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lesomnus/xli"
+	"github.com/lesomnus/xli/flg"
+	"github.com/lesomnus/xli/internal/x"
+)
+
+func TestStyle(t *testing.T) {
+	t.Run("disabled style leaves text as is", x.F(func(x x.X) {
+		s := xli.Style{Palette: xli.DefaultPalette}
+		x.Equal("Usage:", s.Heading("Usage:"))
+	}))
+	t.Run("enabled style wraps text in SGR sequences", x.F(func(x x.X) {
+		s := xli.Style{Palette: xli.Palette{Heading: "1;4"}, Enabled: true}
+		x.Equal("\x1b[1;4mUsage:\x1b[0m", s.Heading("Usage:"))
+		x.Equal("--foo", s.Flag("--foo"))
+	}))
+}
+
+func TestColoredHelp(t *testing.T) {
+	newCmd := func() *xli.Command {
+		return &xli.Command{
+			Name: "app",
+			Flags: flg.Flags{
+				xli.NewFlagColor(),
+				&flg.String{Name: "token", Brief: "access token", Required: true},
+			},
+		}
+	}
+	help := func(c *xli.Command, args ...string) string {
+		b := &strings.Builder{}
+		c.Writer = b
+		err := c.Run(t.Context(), append(args, "--help"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b.String()
+	}
+
+	t.Run("no colors when not a terminal", x.F(func(x x.X) {
+		t.Setenv("NO_COLOR", "")
+		t.Setenv("FORCE_COLOR", "")
+		x.NotContains(help(newCmd()), "\x1b[")
+	}))
+	t.Run("FORCE_COLOR enables colors", x.F(func(x x.X) {
+		t.Setenv("NO_COLOR", "")
+		t.Setenv("FORCE_COLOR", "1")
+		out := help(newCmd())
+		x.Contains(out, "\x1b[1mUsage:\x1b[0m")
+		x.Contains(out, "\x1b[31m(required)\x1b[0m")
+	}))
+	t.Run("NO_COLOR disables colors", x.F(func(x x.X) {
+		t.Setenv("NO_COLOR", "1")
+		t.Setenv("FORCE_COLOR", "1")
+		x.NotContains(help(newCmd()), "\x1b[")
+	}))
+	t.Run("flag takes precedence over the environment", x.F(func(x x.X) {
+		t.Setenv("NO_COLOR", "1")
+		x.Contains(help(newCmd(), "--color=always"), "\x1b[")
+
+		t.Setenv("NO_COLOR", "")
+		t.Setenv("FORCE_COLOR", "1")
+		x.NotContains(help(newCmd(), "--color=never"), "\x1b[")
+	}))
+	t.Run("palette is inherited", x.F(func(x x.X) {
+		c := &xli.Command{
+			Name:    "app",
+			Flags:   flg.Flags{xli.NewFlagColor()},
+			Palette: &xli.Palette{Heading: "4"},
+			Commands: xli.Commands{
+				&xli.Command{Name: "serve"},
+			},
+		}
+		out := help(c, "--color=always", "serve")
+		x.Contains(out, "\x1b[4mUsage:\x1b[0m")
+	}))
+	t.Run("columns are aligned regardless of colors", x.F(func(x x.X) {
+		t.Setenv("COLUMNS", "80")
+		out := help(newCmd(), "--color=always")
+		x.Contains(out, "\x1b[36m   --token string\x1b[0m             access token")
+	}))
+	t.Run("invalid color mode", x.F(func(x x.X) {
+		err := newCmd().Run(t.Context(), []string{"--color=rainbow", "--token=t"})
+		x.True(errors.Is(err, xli.ErrInvalidFlag))
+	}))
+}
+
+func TestPrintError(t *testing.T) {
+	t.Run("plain", x.F(func(x x.X) {
+		t.Setenv("NO_COLOR", "")
+		t.Setenv("FORCE_COLOR", "")
+		b := &strings.Builder{}
+		c := &xli.Command{ErrWriter: b}
+		x.NoError(c.PrintError(errors.New("oops")))
+		x.Equal("error: oops\n", b.String())
+	}))
+	t.Run("colored", x.F(func(x x.X) {
+		t.Setenv("NO_COLOR", "")
+		t.Setenv("FORCE_COLOR", "1")
+		b := &strings.Builder{}
+		c := &xli.Command{ErrWriter: b}
+		x.NoError(c.PrintError(errors.New("oops")))
+		x.Equal("\x1b[1;31merror:\x1b[0m oops\n", b.String())
+	}))
+}
@@ -0,0 +1,38 @@
+package tab
+
+import "strings"
+
+// Directive tells the shell how to complete the candidates of a response.
+// Directives can be combined with "|".
+type Directive uint8
+
+const (
+	// NoSpace keeps the shell from appending a space to the completed word,
+	// e.g. for a candidate like "--flag=" or "key:" that is to be continued.
+	NoSpace Directive = 1 << iota
+	// NoFiles keeps the shell from completing file names when there are no
+	// candidates.
+	NoFiles
+	// KeepOrder keeps the candidates in the order they are given instead of
+	// sorting them.
+	KeepOrder
+	// Dirs makes the shell complete directory names.
+	Dirs
+)
+
+var directive_names = []string{"nospace", "nofiles", "keeporder", "dirs"}
+
+// Is reports whether all of the directives `v` are set in `d`.
+func (d Directive) Is(v Directive) bool {
+	return d&v == v
+}
+
+func (d Directive) String() string {
+	vs := []string{}
+	for i, name := range directive_names {
+		if d&(1<<i) != 0 {
+			vs = append(vs, name)
+		}
+	}
+	return strings.Join(vs, "|")
+}
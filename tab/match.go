@@ -0,0 +1,94 @@
+package tab
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+)
+
+// Match reports whether the candidate `v` matches `prefix`, the part of the
+// word being completed on the left of the cursor.
+type Match func(v string, prefix string) bool
+
+// MatchPrefix matches the candidates that start with the prefix.
+func MatchPrefix(v string, prefix string) bool {
+	return strings.HasPrefix(v, prefix)
+}
+
+// MatchFuzzy matches the candidates that contain the characters of the prefix
+// in order, ignoring case; e.g. "rmv" matches "remove".
+func MatchFuzzy(v string, prefix string) bool {
+	v = strings.ToLower(v)
+	for _, r := range strings.ToLower(prefix) {
+		i := strings.IndexRune(v, r)
+		if i < 0 {
+			return false
+		}
+		v = v[i+utf8.RuneLen(r):]
+	}
+	return true
+}
+
+type prefixCtxKey struct{}
+
+type prefix struct {
+	v string
+	m Match
+}
+
+// Prefix returns the part of the word being completed on the left of the
+// cursor, without the "--flag=" of a flag value. Handlers that query slow
+// sources can use it to narrow the query.
+func Prefix(ctx context.Context) string {
+	v, _ := ctx.Value(prefixCtxKey{}).(prefix)
+	return v.v
+}
+
+// Matches reports whether the candidate `v` matches the prefix in `ctx`.
+func Matches(ctx context.Context, v string) bool {
+	p, ok := ctx.Value(prefixCtxKey{}).(prefix)
+	if !ok {
+		return true
+	}
+	return p.m(v, p.v)
+}
+
+// PrefixInto returns a context with the prefix `v` and the matching `m`, or
+// `MatchPrefix` if it is nil.
+func PrefixInto(ctx context.Context, v string, m Match) context.Context {
+	if m == nil {
+		m = MatchPrefix
+	}
+	return context.WithValue(ctx, prefixCtxKey{}, prefix{v: v, m: m})
+}
+
+type filter struct {
+	Tab
+	prefix string
+	m      Match
+}
+
+// Filter returns a Tab that passes to `t` only the candidates `m`, or
+// `MatchPrefix` if it is nil, matches with `prefix`.
+func Filter(t Tab, prefix string, m Match) Tab {
+	if m == nil {
+		m = MatchPrefix
+	}
+	return &filter{Tab: t, prefix: prefix, m: m}
+}
+
+func (f *filter) Value(v string) {
+	if f.m(v, f.prefix) {
+		f.Tab.Value(v)
+	}
+}
+
+func (f *filter) ValueD(v string, desc string) {
+	if f.m(v, f.prefix) {
+		f.Tab.ValueD(v, desc)
+	}
+}
+
+func (f *filter) Group(name string) Tab {
+	return &filter{Tab: f.Tab.Group(name), prefix: f.prefix, m: f.m}
+}
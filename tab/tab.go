@@ -10,6 +10,9 @@ type Tab interface {
 	// Group returns a Tab whose candidates are shown under the given heading.
 	// Implementations that do not support grouping may return the receiver.
 	Group(name string) Tab
+	// Directive adds hints on how the shell completes the candidates. They
+	// apply to the whole response, whichever group they are given to.
+	Directive(d Directive)
 }
 
 type ctxKey struct{}
@@ -30,6 +30,63 @@ func TestZshTab(t *testing.T) {
 		g.ValueD("port", "the port")
 		x.Equal("net\x1fhost\nnet\x1fport:the port\n", b.String())
 	}))
+	t.Run("colons in values are escaped", x.F(func(x x.X) {
+		b := &strings.Builder{}
+		z := tab.NewZshTab(b)
+		z.Value("a:b")
+		z.ValueD("c:d", "e:f")
+		x.Equal("\x1fa\\:b\n\x1fc\\:d:e:f\n", b.String())
+	}))
+}
+
+func TestDirective(t *testing.T) {
+	t.Run("String", x.F(func(x x.X) {
+		x.Equal("", tab.Directive(0).String())
+		x.Equal("nospace|keeporder", (tab.NoSpace | tab.KeepOrder).String())
+	}))
+	t.Run("Is", x.F(func(x x.X) {
+		d := tab.NoSpace | tab.Dirs
+		x.True(d.Is(tab.NoSpace))
+		x.True(d.Is(tab.NoSpace | tab.Dirs))
+		x.False(d.Is(tab.NoFiles))
+	}))
+	t.Run("ZshTab writes a line of names", x.F(func(x x.X) {
+		b := &strings.Builder{}
+		z := tab.NewZshTab(b)
+		z.Directive(0)
+		z.Group("g").Directive(tab.NoSpace | tab.NoFiles)
+		x.Equal("\x1enospace nofiles\n", b.String())
+	}))
+	t.Run("round-trip", x.F(func(x x.X) {
+		b := &strings.Builder{}
+		z := tab.NewZshTab(b)
+		z.Directive(tab.NoSpace)
+		z.Value("foo")
+		z.Directive(tab.Dirs | tab.KeepOrder)
+		x.Equal(tab.NoSpace|tab.Dirs|tab.KeepOrder, tab.ParseZshDirective(b.String()))
+		x.Equal([]tab.Candidate{{Value: "foo"}}, tab.ParseZsh(b.String()))
+	}))
+	t.Run("filtered Tab passes directives", x.F(func(x x.X) {
+		b := &strings.Builder{}
+		tab.Filter(tab.NewZshTab(b), "x", nil).Directive(tab.NoFiles)
+		x.Equal(tab.NoFiles, tab.ParseZshDirective(b.String()))
+	}))
+}
+
+func TestParseZsh(t *testing.T) {
+	b := &strings.Builder{}
+	z := tab.NewZshTab(b)
+	z.Value("foo")
+	z.ValueD("a:b", "c:d")
+	z.Group("net").ValueD("port", "the port")
+	b.WriteString("\nnot a candidate\n")
+
+	x := x.New(t)
+	x.Equal([]tab.Candidate{
+		{Value: "foo"},
+		{Value: "a:b", Desc: "c:d"},
+		{Group: "net", Value: "port", Desc: "the port"},
+	}, tab.ParseZsh(b.String()))
 }
 
 func TestTabContext(t *testing.T) {
@@ -42,3 +99,48 @@ func TestTabContext(t *testing.T) {
 		x.Same(z, tab.From(ctx))
 	}))
 }
+
+func TestMatch(t *testing.T) {
+	t.Run("prefix", x.F(func(x x.X) {
+		x.True(tab.MatchPrefix("remove", "rem"))
+		x.True(tab.MatchPrefix("remove", ""))
+		x.False(tab.MatchPrefix("remove", "rmv"))
+	}))
+	t.Run("fuzzy", x.F(func(x x.X) {
+		x.True(tab.MatchFuzzy("remove", "rmv"))
+		x.True(tab.MatchFuzzy("Remove", "rE"))
+		x.True(tab.MatchFuzzy("remove", ""))
+		x.False(tab.MatchFuzzy("remove", "vr"))
+	}))
+}
+
+func TestFilter(t *testing.T) {
+	b := &strings.Builder{}
+	f := tab.Filter(tab.NewZshTab(b), "ba", nil)
+	f.Value("foo")
+	f.Value("bar")
+	f.ValueD("baz", "the baz")
+	f.Group("g").Value("bat")
+	f.Group("g").Value("cat")
+
+	x := x.New(t)
+	x.Equal("\x1fbar\n\x1fbaz:the baz\ng\x1fbat\n", b.String())
+}
+
+func TestPrefix(t *testing.T) {
+	t.Run("absent", x.F(func(x x.X) {
+		ctx := context.Background()
+		x.Equal("", tab.Prefix(ctx))
+		x.True(tab.Matches(ctx, "anything"))
+	}))
+	t.Run("prefix matching by default", x.F(func(x x.X) {
+		ctx := tab.PrefixInto(context.Background(), "re", nil)
+		x.Equal("re", tab.Prefix(ctx))
+		x.True(tab.Matches(ctx, "remove"))
+		x.False(tab.Matches(ctx, "add"))
+	}))
+	t.Run("fuzzy", x.F(func(x x.X) {
+		ctx := tab.PrefixInto(context.Background(), "rmv", tab.MatchFuzzy)
+		x.True(tab.Matches(ctx, "remove"))
+	}))
+}
@@ -3,12 +3,18 @@ package tab
 import (
 	"fmt"
 	"io"
+	"slices"
+	"strings"
 )
 
 // zshSep separates the group from the candidate on each emitted line. It is a
 // non-printing byte that survives shell command substitution (unlike NUL).
 const zshSep = "\x1f"
 
+// zshDirectiveSep starts a line of directives, which are written by name
+// separated by spaces.
+const zshDirectiveSep = "\x1e"
+
 type ZshTab struct {
 	io.Writer
 	group string
@@ -19,19 +25,83 @@ func NewZshTab(w io.Writer) *ZshTab {
 }
 
 func (t *ZshTab) Value(v string) {
-	t.emit(v)
+	t.emit(zshEscape(v))
 }
 
 func (t *ZshTab) ValueD(v string, desc string) {
-	t.emit(fmt.Sprintf("%s:%s", v, desc))
+	t.emit(fmt.Sprintf("%s:%s", zshEscape(v), desc))
 }
 
 func (t *ZshTab) Group(name string) Tab {
 	return &ZshTab{Writer: t.Writer, group: name}
 }
 
+func (t *ZshTab) Directive(d Directive) {
+	if d == 0 {
+		return
+	}
+	fmt.Fprintf(t, "%s%s\n", zshDirectiveSep, strings.ReplaceAll(d.String(), "|", " "))
+}
+
 // emit writes one "<group><sep><entry>" line, where entry is "value" or
 // "value:desc". The group may be empty.
 func (t *ZshTab) emit(entry string) {
 	fmt.Fprintf(t, "%s%s%s\n", t.group, zshSep, entry)
 }
+
+// zshEscape escapes the colons of `v` as `_describe` expects, so they are not
+// taken as the start of the description.
+func zshEscape(v string) string {
+	return strings.ReplaceAll(v, ":", `\:`)
+}
+
+// Candidate is a completion candidate read back from the output of a `ZshTab`.
+type Candidate struct {
+	Group string
+	Value string
+	Desc  string
+}
+
+// ParseZsh returns the candidates in `s`, the output of a `ZshTab`. Lines that
+// are not candidates, such as directives, are skipped.
+func ParseZsh(s string) []Candidate {
+	vs := []Candidate{}
+	for _, line := range strings.Split(s, "\n") {
+		group, entry, ok := strings.Cut(line, zshSep)
+		if !ok || entry == "" {
+			continue
+		}
+
+		c := Candidate{Group: group, Value: entry}
+		for i := 0; i < len(entry); i++ {
+			if entry[i] == '\\' && i+1 < len(entry) && entry[i+1] == ':' {
+				i++
+				continue
+			}
+			if entry[i] == ':' {
+				c.Value, c.Desc = entry[:i], entry[i+1:]
+				break
+			}
+		}
+		c.Value = strings.ReplaceAll(c.Value, `\:`, ":")
+		vs = append(vs, c)
+	}
+	return vs
+}
+
+// ParseZshDirective returns the directives in `s`, the output of a `ZshTab`.
+func ParseZshDirective(s string) Directive {
+	var d Directive
+	for _, line := range strings.Split(s, "\n") {
+		names, ok := strings.CutPrefix(line, zshDirectiveSep)
+		if !ok {
+			continue
+		}
+		for _, name := range strings.Fields(names) {
+			if i := slices.Index(directive_names, name); i >= 0 {
+				d |= 1 << i
+			}
+		}
+	}
+	return d
+}
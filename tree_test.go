@@ -2,6 +2,8 @@ package xli_test
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
 
 	"github.com/lesomnus/xli"
@@ -34,3 +36,83 @@ func TestCommandRoot(t *testing.T) {
 		x.Equal("root", got.Name)
 	}))
 }
+
+func TestWalk(t *testing.T) {
+	t.Run("visits commands depth-first with their paths", x.F(func(x x.X) {
+		root := &xli.Command{
+			Name: "root",
+			Commands: xli.Commands{
+				&xli.Command{
+					Name: "a",
+					Commands: xli.Commands{
+						&xli.Command{Name: "b"},
+					},
+				},
+				&xli.Command{Name: "c"},
+			},
+		}
+
+		paths := []string{}
+		err := xli.Walk(root, func(path []*xli.Command) error {
+			names := []string{}
+			for _, c := range path {
+				names = append(names, c.Name)
+			}
+			paths = append(paths, strings.Join(names, " "))
+
+			x.Same(root, path[len(path)-1].Root())
+			return nil
+		})
+		x.NoError(err)
+		x.Equal([]string{"root", "root a", "root a b", "root c"}, paths)
+	}))
+	t.Run("stops on error", x.F(func(x x.X) {
+		root := &xli.Command{
+			Name: "root",
+			Commands: xli.Commands{
+				&xli.Command{Name: "a"},
+				&xli.Command{Name: "b"},
+			},
+		}
+
+		n := 0
+		err := xli.Walk(root, func(path []*xli.Command) error {
+			n++
+			if path[len(path)-1].Name == "a" {
+				return errors.New("stop")
+			}
+			return nil
+		})
+		x.ErrorContains(err, "stop")
+		x.Equal(2, n)
+	}))
+}
+
+func TestHidden(t *testing.T) {
+	newCmd := func() *xli.Command {
+		return &xli.Command{
+			Name: "app",
+			Commands: xli.Commands{
+				&xli.Command{Name: "debug", Hidden: true},
+				&xli.Command{Name: "serve"},
+			},
+		}
+	}
+
+	t.Run("not listed in help", x.F(func(x x.X) {
+		b := &strings.Builder{}
+		c := newCmd()
+		c.Writer = b
+		x.NoError(c.Run(t.Context(), []string{"--help"}))
+		x.Contains(b.String(), "serve")
+		x.NotContains(b.String(), "debug")
+	}))
+	t.Run("not completed", x.F(func(x x.X) {
+		out := complete(t, newCmd(), "", "")
+		x.Contains(out, "serve")
+		x.NotContains(out, "debug")
+	}))
+	t.Run("can be run", x.F(func(x x.X) {
+		x.NoError(newCmd().Run(t.Context(), []string{"debug"}))
+	}))
+}
@@ -0,0 +1,24 @@
+package xli
+
+// Walk calls `f` for `c` and each of its descendants in depth-first order with
+// the commands from `c` to the visited one. It stops and returns the error if
+// `f` returns one. Parents are linked as `Run` does, so `Parent`, `Root`, and
+// `Tree` of the visited commands are meaningful in `f`.
+func Walk(c *Command, f func(path []*Command) error) error {
+	return walk([]*Command{c}, f)
+}
+
+func walk(path []*Command, f func(path []*Command) error) error {
+	if err := f(path); err != nil {
+		return err
+	}
+
+	c := path[len(path)-1]
+	for _, v := range c.Commands {
+		v.parent = c
+		if err := walk(append(path[:len(path):len(path)], v), f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
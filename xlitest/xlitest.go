@@ -0,0 +1,238 @@
+// Package xlitest runs command trees in tests: it captures their output,
+// checks their help against golden files, simulates completion requests, and
+// fakes the environment and the config they read.
+package xlitest
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lesomnus/xli"
+	"github.com/lesomnus/xli/internal/term"
+	"github.com/lesomnus/xli/tab"
+)
+
+// UpdateEnv is the environment variable that makes `GoldenHelp` write the
+// golden files instead of comparing against them, e.g.:
+//
+//	XLITEST_UPDATE=1 go test ./...
+const UpdateEnv = "XLITEST_UPDATE"
+
+// completionTag is the argument the completion scripts pass to request the
+// candidates in the zsh wire format.
+const completionTag = "$$xli_completion_zsh"
+
+// Result is the outcome of a `Run`.
+type Result struct {
+	t testing.TB
+
+	Stdout string
+	Stderr string
+	Err    error
+}
+
+// Code returns the status the program would exit with; see `xli.ExitCode`.
+func (r *Result) Code() int {
+	return xli.ExitCode(r.Err)
+}
+
+// AssertCode fails the test if the exit status is not `code`.
+func (r *Result) AssertCode(code int) *Result {
+	r.t.Helper()
+	if v := r.Code(); v != code {
+		r.t.Errorf("exit status: got %d, want %d (err: %v)\nstderr:\n%s", v, code, r.Err, r.Stderr)
+	}
+	return r
+}
+
+// AssertNoError fails the test if the run failed.
+func (r *Result) AssertNoError() *Result {
+	r.t.Helper()
+	if r.Err != nil {
+		r.t.Errorf("unexpected error: %v\nstderr:\n%s", r.Err, r.Stderr)
+	}
+	return r
+}
+
+// AssertErrorIs fails the test if the error of the run is not `target` or
+// does not wrap it.
+func (r *Result) AssertErrorIs(target error) *Result {
+	r.t.Helper()
+	if !errors.Is(r.Err, target) {
+		r.t.Errorf("error: got %v, want %v", r.Err, target)
+	}
+	return r
+}
+
+// AssertErrorContains fails the test if the run did not fail with an error
+// whose message contains `v`.
+func (r *Result) AssertErrorContains(v string) *Result {
+	r.t.Helper()
+	if r.Err == nil {
+		r.t.Errorf("error: got nil, want one containing %q", v)
+	} else if !strings.Contains(r.Err.Error(), v) {
+		r.t.Errorf("error: got %q, want one containing %q", r.Err.Error(), v)
+	}
+	return r
+}
+
+// Run runs `c` with `args` and an empty stdin, capturing its stdout and
+// stderr. It uses `xli.Command.Invoke`, so `c` is left untouched and can be
+// run again or concurrently.
+func Run(t testing.TB, c *xli.Command, args ...string) *Result {
+	t.Helper()
+	return RunInput(t, c, "", args...)
+}
+
+// RunInput is `Run` with `stdin` as the input.
+func RunInput(t testing.TB, c *xli.Command, stdin string, args ...string) *Result {
+	t.Helper()
+	o := &bytes.Buffer{}
+	e := &bytes.Buffer{}
+	err := c.Invoke(t.Context(), args, xli.Invocation{
+		ReadCloser: io.NopCloser(strings.NewReader(stdin)),
+		Writer:     o,
+		ErrWriter:  e,
+	})
+	return &Result{t: t, Stdout: o.String(), Stderr: e.String(), Err: err}
+}
+
+// Complete requests the candidates for completing `curr` after `args` as the
+// completion script does, with `buff` as the text left of the cursor, and
+// returns them decoded from the wire format. The test fails if the request
+// does.
+func Complete(t testing.TB, c *xli.Command, curr string, buff string, args ...string) []tab.Candidate {
+	t.Helper()
+	vs, _ := CompleteAll(t, c, curr, buff, args...)
+	return vs
+}
+
+// CompleteAll is `Complete` that also returns the directives of the response.
+func CompleteAll(t testing.TB, c *xli.Command, curr string, buff string, args ...string) ([]tab.Candidate, tab.Directive) {
+	t.Helper()
+	args = append(slices.Clone(args), completionTag, curr, buff)
+	r := Run(t, c, args...)
+	if r.Err != nil {
+		t.Fatalf("completion of %q: %v", curr, r.Err)
+	}
+	return tab.ParseZsh(r.Stdout), tab.ParseZshDirective(r.Stdout)
+}
+
+// Values returns the values of `cs`.
+func Values(cs []tab.Candidate) []string {
+	vs := make([]string, len(cs))
+	for i, c := range cs {
+		vs[i] = c.Value
+	}
+	return vs
+}
+
+// GoldenHelp compares the help of `c` and each of its descendants with the
+// golden files in `dir`, named after the path of the command, e.g.
+// "app/remote/add.golden". The help is rendered from `xli.NewHelpData`
+// without colors at the default width, so it does not list the user aliases
+// and plugins found on the machine. The files are written instead if
+// `UpdateEnv` is set.
+func GoldenHelp(t *testing.T, c *xli.Command, dir string) {
+	t.Helper()
+	Env(t, map[string]string{
+		"COLUMNS":     strconv.Itoa(term.DefaultWidth),
+		"NO_COLOR":    "1",
+		"FORCE_COLOR": "",
+	})
+	update := os.Getenv(UpdateEnv) != ""
+
+	err := xli.Walk(c, func(path []*xli.Command) error {
+		names := make([]string, len(path))
+		for i, v := range path {
+			names[i] = v.Name
+		}
+		p := filepath.Join(dir, filepath.Join(names...)+".golden")
+
+		b := &bytes.Buffer{}
+		c := path[len(path)-1]
+		if err := c.RenderHelp(b, xli.NewHelpData(c)); err != nil {
+			t.Errorf("%s: print help: %v", strings.Join(names, " "), err)
+			return nil
+		}
+
+		if update {
+			if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+				return err
+			}
+			return os.WriteFile(p, b.Bytes(), 0o644)
+		}
+
+		want, err := os.ReadFile(p)
+		if err != nil {
+			t.Errorf("%s: %v; run with %s=1 to create it", strings.Join(names, " "), err, UpdateEnv)
+			return nil
+		}
+		if !bytes.Equal(want, b.Bytes()) {
+			t.Errorf("%s: help differs from %s; run with %s=1 to update it\ngot:\n%s\nwant:\n%s", strings.Join(names, " "), p, UpdateEnv, b, want)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("write golden files: %v", err)
+	}
+}
+
+// Env sets the environment variables `vs` for the rest of the test and
+// restores them after it. An empty value stands for an unset variable, which
+// xli does not tell apart. Like `testing.T.Setenv`, it cannot be used in
+// parallel tests.
+func Env(t testing.TB, vs map[string]string) {
+	t.Helper()
+	for k, v := range vs {
+		t.Setenv(k, v)
+	}
+}
+
+// Aliases is an `xli.UserAliasStore` kept in memory. `Err`, if set, is
+// returned by both `Load` and `Save` to fake a broken config.
+type Aliases struct {
+	mu sync.Mutex
+
+	Aliases []xli.UserAlias
+	Err     error
+}
+
+// NewAliases returns a store holding `vs`.
+func NewAliases(vs ...xli.UserAlias) *Aliases {
+	return &Aliases{Aliases: vs}
+}
+
+func (s *Aliases) Load() ([]xli.UserAlias, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	return slices.Clone(s.Aliases), nil
+}
+
+func (s *Aliases) Save(vs []xli.UserAlias) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return s.Err
+	}
+	s.Aliases = slices.Clone(vs)
+	return nil
+}
+
+// Get returns the aliases saved last.
+func (s *Aliases) Get() []xli.UserAlias {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.Aliases)
+}
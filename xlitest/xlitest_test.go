@@ -0,0 +1,142 @@
+package xlitest_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/lesomnus/xli"
+	"github.com/lesomnus/xli/arg"
+	"github.com/lesomnus/xli/flg"
+	"github.com/lesomnus/xli/internal/x"
+	"github.com/lesomnus/xli/tab"
+	"github.com/lesomnus/xli/xlitest"
+)
+
+var errBroken = errors.New("broken")
+
+func newCmd() *xli.Command {
+	return &xli.Command{
+		Name:  "app",
+		Brief: "app-brief",
+		Flags: flg.Flags{
+			&flg.String{Name: "mode", Brief: "mode-brief", Handler: flg.OnTab[string](func(ctx context.Context, t tab.Tab) error {
+				t.ValueD("a:b", "colon")
+				t.Value("plain")
+				return nil
+			})},
+		},
+		Commands: xli.Commands{
+			&xli.Command{
+				Name:  "cat",
+				Brief: "cat-brief",
+				Handler: xli.OnRun(func(ctx context.Context, cmd *xli.Command, next xli.Next) error {
+					b, err := io.ReadAll(cmd)
+					if err != nil {
+						return err
+					}
+					cmd.Print(string(b))
+					return next(ctx)
+				}),
+			},
+			&xli.Command{
+				Name:  "fail",
+				Brief: "fail-brief",
+				Args:  arg.Args{&arg.String{Name: "WHY", Brief: "why-brief"}},
+				Handler: xli.OnRun(func(ctx context.Context, cmd *xli.Command, next xli.Next) error {
+					cmd.ErrWriter.Write([]byte("failing\n"))
+					return errBroken
+				}),
+			},
+			xli.NewCmdAlias(),
+		},
+	}
+}
+
+func TestRun(t *testing.T) {
+	t.Run("stdin and stdout", x.F(func(x x.X) {
+		c := newCmd()
+		r := xlitest.RunInput(t, c, "hello", "cat").AssertNoError().AssertCode(0)
+		x.Equal("hello", r.Stdout)
+		x.Empty(r.Stderr)
+
+		// The tree can be run again.
+		r = xlitest.RunInput(t, c, "again", "cat").AssertNoError()
+		x.Equal("again", r.Stdout)
+	}))
+	t.Run("error and stderr", x.F(func(x x.X) {
+		r := xlitest.Run(t, newCmd(), "fail", "now").AssertCode(1).AssertErrorIs(errBroken)
+		x.Equal("failing\n", r.Stderr)
+	}))
+	t.Run("usage error", x.F(func(x x.X) {
+		xlitest.Run(t, newCmd(), "foo").AssertErrorContains("unknown subcommand")
+	}))
+}
+
+func TestComplete(t *testing.T) {
+	t.Run("subcommands", x.F(func(x x.X) {
+		vs := xlitest.Values(xlitest.Complete(t, newCmd(), "", ""))
+		x.True(slices.Contains(vs, "cat"))
+		x.True(slices.Contains(vs, "fail"))
+	}))
+	t.Run("values are decoded", x.F(func(x x.X) {
+		cs := xlitest.Complete(t, newCmd(), "", "", "--mode")
+		x.Equal([]tab.Candidate{
+			{Value: "a:b", Desc: "colon"},
+			{Value: "plain"},
+		}, cs)
+	}))
+	t.Run("directives", x.F(func(x x.X) {
+		_, d := xlitest.CompleteAll(t, newCmd(), "", "")
+		x.Equal(tab.NoFiles, d)
+	}))
+}
+
+func TestGoldenHelp(t *testing.T) {
+	t.Run("tree", func(t *testing.T) {
+		xlitest.GoldenHelp(t, newCmd(), "testdata")
+	})
+	t.Run("user aliases and plugins are left out", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "app-plug"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		c := newCmd()
+		c.UserAliases = xlitest.NewAliases(xli.UserAlias{Name: "c", Args: []string{"cat"}})
+		c.Plugins = xli.PathPlugins{Dirs: []string{dir}}
+		xlitest.GoldenHelp(t, c, "testdata")
+	})
+}
+
+func TestEnv(t *testing.T) {
+	t.Setenv("XLITEST_FOO", "outer")
+	t.Run("set", func(t *testing.T) {
+		xlitest.Env(t, map[string]string{"XLITEST_FOO": "inner"})
+		x.New(t).Equal("inner", os.Getenv("XLITEST_FOO"))
+	})
+	x.New(t).Equal("outer", os.Getenv("XLITEST_FOO"))
+}
+
+func TestAliases(t *testing.T) {
+	t.Run("loaded and saved", x.F(func(x x.X) {
+		s := xlitest.NewAliases(xli.UserAlias{Name: "c", Args: []string{"cat"}})
+		c := newCmd()
+		c.UserAliases = s
+
+		r := xlitest.RunInput(t, c, "hi", "c").AssertNoError()
+		x.Equal("hi", r.Stdout)
+
+		xlitest.Run(t, c, "alias", "set", "f", "fail").AssertNoError()
+		x.Len(s.Get(), 2)
+	}))
+	t.Run("broken", x.F(func(x x.X) {
+		c := newCmd()
+		c.UserAliases = &xlitest.Aliases{Err: errBroken}
+		xlitest.Run(t, c, "c").AssertErrorIs(errBroken)
+	}))
+}